@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"time"
+	_ "time/tzdata"
 
 	connect "github.com/bufbuild/connect-go"
 	"github.com/bufbuild/protovalidate-go"
@@ -27,6 +28,13 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export", "import":
+			os.Exit(runScheduleCommand(ctx, os.Args[1], os.Args[2:]))
+		}
+	}
+
 	cfg, err := config.LoadConfig(ctx)
 	if err != nil {
 		slog.Error("failed to load configuration", slog.Any("error", err.Error()))
@@ -51,16 +59,25 @@ func main() {
 		validator.NewInterceptor(protoValidator),
 	)
 
+	// roleResolver is used to match the admin roles of the extension service
+	// by name. Without the role service, only role IDs are matched.
+	var roleResolver auth.RoleResolverFunc
+
 	if roleClient, err := wellknown.RoleService.Create(ctx, catalog); err == nil {
+		roleResolver = auth.NewIDMRoleResolver(roleClient)
+
 		authInterceptor := auth.NewAuthAnnotationInterceptor(
 			protoregistry.GlobalFiles,
-			auth.NewIDMRoleResolver(roleClient),
+			roleResolver,
 			auth.RemoteHeaderExtractor,
 		)
 
 		interceptors = connect.WithOptions(interceptors, connect.WithInterceptors(authInterceptor))
 	}
 
+	// record the authenticated user for the audit log
+	interceptors = connect.WithOptions(interceptors, connect.WithInterceptors(service.NewActorInterceptor()))
+
 	corsConfig := cors.Config{
 		AllowedOrigins:   cfg.AllowedOrigins,
 		AllowCredentials: true,
@@ -79,6 +96,15 @@ func main() {
 	path, handler := office_hoursv1connect.NewOfficeHourServiceHandler(svc, interceptors)
 	serveMux.Handle(path, handler)
 
+	// The extension service uses JSON encoded messages so we cannot use the
+	// validator and auth-annotation interceptors here.
+	path, handler = service.NewExtensionServiceHandler(svc, connect.WithInterceptors(
+		log.NewLoggingInterceptor(),
+		service.NewExtensionAuthInterceptor(cfg.AdminRoles, roleResolver),
+		service.NewActorInterceptor(),
+	))
+	serveMux.Handle(path, handler)
+
 	loggingHandler := func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
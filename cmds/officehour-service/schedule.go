@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/config"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// runScheduleCommand runs the export or import subcommand using the
+// database configured by the environment and returns the exit code.
+//
+//	officehour-service export [-format yaml|json] [-o file]
+//	officehour-service import [-format yaml|json] [-dry-run] file
+func runScheduleCommand(ctx context.Context, cmd string, args []string) int {
+	flags := flag.NewFlagSet(cmd, flag.ContinueOnError)
+
+	format := flags.String("format", "", "document format, either yaml or json (defaults to the file extension or yaml)")
+	output := flags.String("o", "", "write the exported document to this file instead of stdout")
+	dryRun := flags.Bool("dry-run", false, "only print the changes without applying them")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load configuration: %s\n", err)
+		return 1
+	}
+
+	r, err := cfg.OpenStore(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open store: %s\n", err)
+		return 1
+	}
+
+	// record imports using the local user in the audit log
+	ctx = repo.WithActor(ctx, repo.Actor{
+		Username: os.Getenv("USER"),
+	})
+
+	switch cmd {
+	case "export":
+		err = exportSchedule(ctx, r, formatFor(*format, *output), *output)
+
+	case "import":
+		if flags.NArg() != 1 {
+			fmt.Fprintf(os.Stderr, "usage: %s import [-format yaml|json] [-dry-run] file\n", os.Args[0])
+			return 2
+		}
+
+		err = importSchedule(ctx, r, formatFor(*format, flags.Arg(0)), flags.Arg(0), *dryRun)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s failed: %s\n", cmd, err)
+		return 1
+	}
+
+	return 0
+}
+
+func exportSchedule(ctx context.Context, r repo.OfficeHourStore, format string, output string) error {
+	doc, err := r.ExportSchedule(ctx)
+	if err != nil {
+		return err
+	}
+
+	blob, err := doc.Encode(format)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(blob)
+		return err
+	}
+
+	return os.WriteFile(output, blob, 0o644)
+}
+
+func importSchedule(ctx context.Context, r repo.OfficeHourStore, format string, path string, dryRun bool) error {
+	var (
+		blob []byte
+		err  error
+	)
+
+	if path == "-" {
+		blob, err = io.ReadAll(os.Stdin)
+	} else {
+		blob, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	doc, err := repo.DecodeScheduleDocument(blob, format)
+	if err != nil {
+		return err
+	}
+
+	if err := doc.Validate(); err != nil {
+		return err
+	}
+
+	diff, version, err := r.ImportSchedule(ctx, doc, dryRun)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(diff); err != nil {
+		return err
+	}
+
+	if version != nil {
+		fmt.Fprintf(os.Stderr, "imported schedule as version %d\n", version.Version)
+	}
+
+	return nil
+}
+
+// formatFor returns format or, if empty, the format matching the extension
+// of path.
+func formatFor(format string, path string) string {
+	if format != "" {
+		return format
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return repo.FormatJSON
+	}
+
+	return repo.FormatYAML
+}
@@ -0,0 +1,364 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.35.1
+// 	protoc        (unknown)
+// source: tkd/office_hours/v1/location_events.proto
+
+package office_hoursv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// LocationOpenChangeEvent is published whenever the open state or the opening
+// mode of a location changes. In contrast to the OpenChangeEvent, which does
+// not carry a location and is thus only published for the default location,
+// it is published for every location.
+type LocationOpenChangeEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Location is the location whose state changed. It is empty for the
+	// default location.
+	Location string `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	// IsOpen is true if the location is open.
+	IsOpen bool `protobuf:"varint,2,opt,name=is_open,json=isOpen,proto3" json:"is_open,omitempty"`
+	// Mode is the opening mode that applies while the location is open. If
+	// the location is closed, it is "phone-only" if the location can still
+	// be reached by phone and empty otherwise.
+	Mode string `protobuf:"bytes,3,opt,name=mode,proto3" json:"mode,omitempty"`
+	// OfficeHour is the name of the office hour that applies while the
+	// location is open.
+	OfficeHour string `protobuf:"bytes,4,opt,name=office_hour,json=officeHour,proto3" json:"office_hour,omitempty"`
+	// Since is the time at which the new state began.
+	Since *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=since,proto3" json:"since,omitempty"`
+	// Override is true if the state is forced by a manual override.
+	Override bool `protobuf:"varint,6,opt,name=override,proto3" json:"override,omitempty"`
+}
+
+func (x *LocationOpenChangeEvent) Reset() {
+	*x = LocationOpenChangeEvent{}
+	mi := &file_tkd_office_hours_v1_location_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LocationOpenChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LocationOpenChangeEvent) ProtoMessage() {}
+
+func (x *LocationOpenChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_tkd_office_hours_v1_location_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LocationOpenChangeEvent.ProtoReflect.Descriptor instead.
+func (*LocationOpenChangeEvent) Descriptor() ([]byte, []int) {
+	return file_tkd_office_hours_v1_location_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *LocationOpenChangeEvent) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *LocationOpenChangeEvent) GetIsOpen() bool {
+	if x != nil {
+		return x.IsOpen
+	}
+	return false
+}
+
+func (x *LocationOpenChangeEvent) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *LocationOpenChangeEvent) GetOfficeHour() string {
+	if x != nil {
+		return x.OfficeHour
+	}
+	return ""
+}
+
+func (x *LocationOpenChangeEvent) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *LocationOpenChangeEvent) GetOverride() bool {
+	if x != nil {
+		return x.Override
+	}
+	return false
+}
+
+// ClosingWarningEvent is published a configured lead time before a location
+// closes.
+type ClosingWarningEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Location is the location that closes. It is empty for the default
+	// location.
+	Location string `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	// ClosesAt is the time at which the location closes.
+	ClosesAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=closes_at,json=closesAt,proto3" json:"closes_at,omitempty"`
+	// LeadTime is how long before the location closes the event has been
+	// published.
+	LeadTime *durationpb.Duration `protobuf:"bytes,3,opt,name=lead_time,json=leadTime,proto3" json:"lead_time,omitempty"`
+}
+
+func (x *ClosingWarningEvent) Reset() {
+	*x = ClosingWarningEvent{}
+	mi := &file_tkd_office_hours_v1_location_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClosingWarningEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClosingWarningEvent) ProtoMessage() {}
+
+func (x *ClosingWarningEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_tkd_office_hours_v1_location_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClosingWarningEvent.ProtoReflect.Descriptor instead.
+func (*ClosingWarningEvent) Descriptor() ([]byte, []int) {
+	return file_tkd_office_hours_v1_location_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ClosingWarningEvent) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *ClosingWarningEvent) GetClosesAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ClosesAt
+	}
+	return nil
+}
+
+func (x *ClosingWarningEvent) GetLeadTime() *durationpb.Duration {
+	if x != nil {
+		return x.LeadTime
+	}
+	return nil
+}
+
+// OpeningWarningEvent is published a configured lead time before a location
+// opens.
+type OpeningWarningEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Location is the location that opens. It is empty for the default
+	// location.
+	Location string `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	// OpensAt is the time at which the location opens.
+	OpensAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=opens_at,json=opensAt,proto3" json:"opens_at,omitempty"`
+	// LeadTime is how long before the location opens the event has been
+	// published.
+	LeadTime *durationpb.Duration `protobuf:"bytes,3,opt,name=lead_time,json=leadTime,proto3" json:"lead_time,omitempty"`
+}
+
+func (x *OpeningWarningEvent) Reset() {
+	*x = OpeningWarningEvent{}
+	mi := &file_tkd_office_hours_v1_location_events_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OpeningWarningEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OpeningWarningEvent) ProtoMessage() {}
+
+func (x *OpeningWarningEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_tkd_office_hours_v1_location_events_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OpeningWarningEvent.ProtoReflect.Descriptor instead.
+func (*OpeningWarningEvent) Descriptor() ([]byte, []int) {
+	return file_tkd_office_hours_v1_location_events_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OpeningWarningEvent) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *OpeningWarningEvent) GetOpensAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OpensAt
+	}
+	return nil
+}
+
+func (x *OpeningWarningEvent) GetLeadTime() *durationpb.Duration {
+	if x != nil {
+		return x.LeadTime
+	}
+	return nil
+}
+
+var File_tkd_office_hours_v1_location_events_proto protoreflect.FileDescriptor
+
+var file_tkd_office_hours_v1_location_events_proto_rawDesc = []byte{
+	0x0a, 0x29, 0x74, 0x6b, 0x64, 0x2f, 0x6f, 0x66, 0x66, 0x69, 0x63, 0x65, 0x5f, 0x68, 0x6f, 0x75,
+	0x72, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x65,
+	0x76, 0x65, 0x6e, 0x74, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x13, 0x74, 0x6b, 0x64,
+	0x2e, 0x6f, 0x66, 0x66, 0x69, 0x63, 0x65, 0x5f, 0x68, 0x6f, 0x75, 0x72, 0x73, 0x2e, 0x76, 0x31,
+	0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x22, 0xd1, 0x01, 0x0a, 0x17, 0x4c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x4f, 0x70,
+	0x65, 0x6e, 0x43, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1a, 0x0a,
+	0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x17, 0x0a, 0x07, 0x69, 0x73, 0x5f,
+	0x6f, 0x70, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x69, 0x73, 0x4f, 0x70,
+	0x65, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x6f, 0x66, 0x66, 0x69, 0x63, 0x65,
+	0x5f, 0x68, 0x6f, 0x75, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x6f, 0x66, 0x66,
+	0x69, 0x63, 0x65, 0x48, 0x6f, 0x75, 0x72, 0x12, 0x30, 0x0a, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x6f, 0x76, 0x65,
+	0x72, 0x72, 0x69, 0x64, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x6f, 0x76, 0x65,
+	0x72, 0x72, 0x69, 0x64, 0x65, 0x22, 0xa2, 0x01, 0x0a, 0x13, 0x43, 0x6c, 0x6f, 0x73, 0x69, 0x6e,
+	0x67, 0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1a, 0x0a,
+	0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x37, 0x0a, 0x09, 0x63, 0x6c, 0x6f,
+	0x73, 0x65, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x08, 0x63, 0x6c, 0x6f, 0x73, 0x65, 0x73,
+	0x41, 0x74, 0x12, 0x36, 0x0a, 0x09, 0x6c, 0x65, 0x61, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x08, 0x6c, 0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x22, 0xa0, 0x01, 0x0a, 0x13, 0x4f,
+	0x70, 0x65, 0x6e, 0x69, 0x6e, 0x67, 0x57, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x45, 0x76, 0x65,
+	0x6e, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x35,
+	0x0a, 0x08, 0x6f, 0x70, 0x65, 0x6e, 0x73, 0x5f, 0x61, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x07, 0x6f, 0x70,
+	0x65, 0x6e, 0x73, 0x41, 0x74, 0x12, 0x36, 0x0a, 0x09, 0x6c, 0x65, 0x61, 0x64, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x08, 0x6c, 0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_tkd_office_hours_v1_location_events_proto_rawDescOnce sync.Once
+	file_tkd_office_hours_v1_location_events_proto_rawDescData = file_tkd_office_hours_v1_location_events_proto_rawDesc
+)
+
+func file_tkd_office_hours_v1_location_events_proto_rawDescGZIP() []byte {
+	file_tkd_office_hours_v1_location_events_proto_rawDescOnce.Do(func() {
+		file_tkd_office_hours_v1_location_events_proto_rawDescData = protoimpl.X.CompressGZIP(file_tkd_office_hours_v1_location_events_proto_rawDescData)
+	})
+	return file_tkd_office_hours_v1_location_events_proto_rawDescData
+}
+
+var file_tkd_office_hours_v1_location_events_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_tkd_office_hours_v1_location_events_proto_goTypes = []any{
+	(*LocationOpenChangeEvent)(nil), // 0: tkd.office_hours.v1.LocationOpenChangeEvent
+	(*ClosingWarningEvent)(nil),     // 1: tkd.office_hours.v1.ClosingWarningEvent
+	(*OpeningWarningEvent)(nil),     // 2: tkd.office_hours.v1.OpeningWarningEvent
+	(*timestamppb.Timestamp)(nil),   // 3: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),     // 4: google.protobuf.Duration
+}
+var file_tkd_office_hours_v1_location_events_proto_depIdxs = []int32{
+	3, // 0: tkd.office_hours.v1.LocationOpenChangeEvent.since:type_name -> google.protobuf.Timestamp
+	3, // 1: tkd.office_hours.v1.ClosingWarningEvent.closes_at:type_name -> google.protobuf.Timestamp
+	4, // 2: tkd.office_hours.v1.ClosingWarningEvent.lead_time:type_name -> google.protobuf.Duration
+	3, // 3: tkd.office_hours.v1.OpeningWarningEvent.opens_at:type_name -> google.protobuf.Timestamp
+	4, // 4: tkd.office_hours.v1.OpeningWarningEvent.lead_time:type_name -> google.protobuf.Duration
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_tkd_office_hours_v1_location_events_proto_init() }
+func file_tkd_office_hours_v1_location_events_proto_init() {
+	if File_tkd_office_hours_v1_location_events_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_tkd_office_hours_v1_location_events_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_tkd_office_hours_v1_location_events_proto_goTypes,
+		DependencyIndexes: file_tkd_office_hours_v1_location_events_proto_depIdxs,
+		MessageInfos:      file_tkd_office_hours_v1_location_events_proto_msgTypes,
+	}.Build()
+	File_tkd_office_hours_v1_location_events_proto = out.File
+	file_tkd_office_hours_v1_location_events_proto_rawDesc = nil
+	file_tkd_office_hours_v1_location_events_proto_goTypes = nil
+	file_tkd_office_hours_v1_location_events_proto_depIdxs = nil
+}
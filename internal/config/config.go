@@ -3,12 +3,18 @@ package config
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/sethvargo/go-envconfig"
 	"github.com/tierklinik-dobersberg/apis/pkg/discovery"
 	"github.com/tierklinik-dobersberg/apis/pkg/discovery/wellknown"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/drafts"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/holidays"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/leader"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/trash"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/watcher"
 )
 
@@ -16,8 +22,86 @@ type Config struct {
 	AllowedOrigins []string `env:"ALLOWED_ORIGINS,default=*"`
 	ListenAddress  string   `env:"LISTEN,default=:8081"`
 
-	MongoURL string `env:"MONGO_URL,required"`
+	// AdminRoles holds the IDs or names of the roles that may modify office
+	// hours, overrides, drafts and the trash using the extension service.
+	// Role names are only matched if the role service is available.
+	AdminRoles []string `env:"ADMIN_ROLES,default=idm_superuser"`
+
+	// Timezone is the IANA name of the timezone in which office hours are
+	// interpreted.
+	Timezone string `env:"TIMEZONE,default=Europe/Vienna"`
+
+	// Storage selects where office hours are stored. Supported values are
+	// "mongo", "memory" and "file". The memory and file storage only
+	// support a single replica.
+	Storage string `env:"STORAGE,default=mongo"`
+
+	// StorageFile is the path of the JSON or YAML file used by the file
+	// storage.
+	StorageFile string `env:"STORAGE_FILE,default=office-hours.yaml"`
+
+	MongoURL string `env:"MONGO_URL"`
 	Database string `env:"DATABASE,default=cis"`
+
+	// MongoAllowNonAtomic allows to use a standalone MongoDB server that
+	// does not support transactions. Changes and their audit entries,
+	// schedule versions and imports are then not written atomically.
+	MongoAllowNonAtomic bool `env:"MONGO_ALLOW_NON_ATOMIC,default=false"`
+
+	// NextChangeHorizon limits how far into the future the NextChange RPC
+	// searches for the next opening or closing time.
+	NextChangeHorizon time.Duration `env:"NEXT_CHANGE_HORIZON,default=1440h"`
+
+	// Holidays may hold a list of public holidays (YYYY-MM-DD or MM-DD for
+	// yearly recurring ones) that are used when the calendar service is
+	// unavailable.
+	Holidays []string `env:"HOLIDAYS"`
+
+	// HolidaysFile may point to an iCalendar file with public holidays that
+	// is used when the calendar service is unavailable. Takes precedence over
+	// Holidays.
+	HolidaysFile string `env:"HOLIDAYS_FILE"`
+
+	// HolidayCacheTTL configures how long public holidays are cached. Set
+	// to zero to disable caching. Holidays from the offline fallback, used
+	// while the calendar service is unavailable, are cached for at most a
+	// minute.
+	HolidayCacheTTL time.Duration `env:"HOLIDAY_CACHE_TTL,default=6h"`
+
+	// ClosingWarnings and OpeningWarnings configure how long before a
+	// location closes or opens a warning event is published.
+	ClosingWarnings []time.Duration `env:"CLOSING_WARNINGS"`
+	OpeningWarnings []time.Duration `env:"OPENING_WARNINGS"`
+
+	// LeaderLeaseTTL configures how long the leadership is valid without
+	// being renewed. Only the leader publishes events and scheduled drafts
+	// and a standby replica takes over at most LeaderLeaseTTL after the
+	// leader died.
+	LeaderLeaseTTL time.Duration `env:"LEADER_LEASE_TTL,default=30s"`
+
+	// TrashRetention configures how long deleted office hours are kept in
+	// the trash before they are purged. Set to zero to keep them forever.
+	TrashRetention time.Duration `env:"TRASH_RETENTION,default=720h"`
+
+	// ScheduleVersionRetention configures how many schedule versions are
+	// kept for the version history and rollbacks. Every change stores a copy
+	// of the whole schedule. Set to zero to keep all versions.
+	ScheduleVersionRetention int `env:"SCHEDULE_VERSION_RETENTION,default=0"`
+
+	// EventDeliveryAttempts configures how often delivering an event to the
+	// event service is attempted before the event is given up. Failed
+	// deliveries are retried at most every five minutes. Given up events
+	// are kept in the outbox with their last delivery error for 30 days.
+	// Set to zero to retry events forever.
+	EventDeliveryAttempts int `env:"EVENT_DELIVERY_ATTEMPTS,default=0"`
+
+	// OutboxPollInterval configures how often the leader checks for events
+	// enqueued by other replicas. With MongoDB change streams those events
+	// are delivered immediately and polling is only a fallback. Without,
+	// an event is delivered at most OutboxPollInterval after it has been
+	// enqueued, or up to five minutes after the event service recovered
+	// from an outage.
+	OutboxPollInterval time.Duration `env:"OUTBOX_POLL_INTERVAL,default=1m"`
 }
 
 func LoadConfig(ctx context.Context) (*Config, error) {
@@ -27,35 +111,149 @@ func LoadConfig(ctx context.Context) (*Config, error) {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
+	if cfg.LeaderLeaseTTL <= 0 {
+		return nil, fmt.Errorf("invalid config: LEADER_LEASE_TTL must be positive")
+	}
+
+	if cfg.ScheduleVersionRetention < 0 {
+		return nil, fmt.Errorf("invalid config: SCHEDULE_VERSION_RETENTION must not be negative")
+	}
+
+	if cfg.EventDeliveryAttempts < 0 {
+		return nil, fmt.Errorf("invalid config: EVENT_DELIVERY_ATTEMPTS must not be negative")
+	}
+
+	if cfg.OutboxPollInterval <= 0 {
+		return nil, fmt.Errorf("invalid config: OUTBOX_POLL_INTERVAL must be positive")
+	}
+
 	return &cfg, nil
 }
 
+// OpenStore returns the office-hour store selected by cfg.Storage.
+func (cfg *Config) OpenStore(ctx context.Context) (repo.OfficeHourStore, error) {
+	switch cfg.Storage {
+	case "mongo", "":
+		if cfg.MongoURL == "" {
+			return nil, fmt.Errorf("MONGO_URL is required for the mongo storage")
+		}
+
+		r, err := repo.NewRepo(ctx, cfg.MongoURL, cfg.Database, cfg.MongoAllowNonAtomic)
+		if err != nil {
+			return nil, err
+		}
+
+		r.SetVersionRetention(cfg.ScheduleVersionRetention)
+
+		return r, nil
+
+	case "memory":
+		m := repo.NewMemoryStore()
+		m.SetVersionRetention(cfg.ScheduleVersionRetention)
+
+		return m, nil
+
+	case "file":
+		f, err := repo.NewFileStore(cfg.StorageFile)
+		if err != nil {
+			return nil, err
+		}
+
+		f.SetVersionRetention(cfg.ScheduleVersionRetention)
+
+		return f, nil
+	}
+
+	return nil, fmt.Errorf("unsupported storage %q", cfg.Storage)
+}
+
 func (cfg *Config) ConfigureProviders(ctx context.Context, catalog discovery.Discoverer) (*Providers, error) {
-	repo, err := repo.NewRepo(ctx, cfg.MongoURL, cfg.Database)
+	tz, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+	}
+
+	store, err := cfg.OpenStore(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	resolver := resolver.NewResolver(repo, catalog)
+	var fallback resolver.HolidayProvider
+	switch {
+	case cfg.HolidaysFile != "":
+		fallback, err = holidays.LoadICSFile(cfg.HolidaysFile)
+
+	case len(cfg.Holidays) == 0:
+		slog.Warn("no offline holidays configured, public holidays are unknown while the calendar service is unavailable")
 
-	var w *watcher.Watcher
+		fallback, err = holidays.NewStaticProvider(nil)
 
+	default:
+		fallback, err = holidays.NewStaticProvider(cfg.Holidays)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load static holidays: %w", err)
+	}
+
+	resolver := resolver.NewResolver(store, holidays.NewCompositeProvider(
+		holidays.NewRemoteProvider(catalog),
+		fallback,
+	), cfg.HolidayCacheTTL, tz)
+
+	// The event service is optional. Without it, the watcher only notifies
+	// subscribers about open state changes.
 	cli, err := wellknown.EventService.Create(ctx, catalog)
-	if err == nil {
-		w = watcher.New(
-			resolver,
-			cli,
-		)
+	if err != nil {
+		cli = nil
+	}
+
+	w := watcher.New(
+		store,
+		resolver,
+		cli,
+		tz,
+		watcher.LeadTimes{
+			Closing: cfg.ClosingWarnings,
+			Opening: cfg.OpeningWarnings,
+		},
+	)
+
+	w.SetMaxDeliveryAttempts(cfg.EventDeliveryAttempts)
+	w.SetPollInterval(cfg.OutboxPollInterval)
+	w.Start(ctx)
+
+	scheduler := drafts.NewScheduler(store, w.Trigger)
 
-		// Immediately start the watcher
-		w.Start(ctx)
+	// Re-check the open state whenever the office hours are changed by
+	// another replica or directly in the database, look for due drafts
+	// whenever a draft is scheduled and deliver events enqueued by other
+	// replicas immediately.
+	if cw, ok := store.(repo.ChangeWatcher); ok {
+		cw.WatchChanges(ctx, w.Trigger, scheduler.Trigger, w.Dispatch)
 	}
 
+	purger := trash.NewPurger(store, cfg.TrashRetention)
+
+	// Only publish events and scheduled drafts and purge the trash while
+	// this replica is the leader
+	elector, err := leader.New(store, "watcher", cfg.LeaderLeaseTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	elector.Run(ctx, func(ctx context.Context) {
+		w.Lead(ctx)
+		scheduler.Start(ctx)
+		purger.Start(ctx)
+	})
+
 	return &Providers{
 		Config:   cfg,
-		Repo:     repo,
+		Store:    store,
 		Resolver: resolver,
 		Watcher:  w,
+		Drafts:   scheduler,
+		TimeZone: tz,
 
 		Catalog: catalog,
 	}, nil
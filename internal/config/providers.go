@@ -1,7 +1,10 @@
 package config
 
 import (
+	"time"
+
 	"github.com/tierklinik-dobersberg/apis/pkg/discovery"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/drafts"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/watcher"
@@ -10,9 +13,13 @@ import (
 type Providers struct {
 	*Config
 
-	Repo     *repo.Repo
+	Store    repo.OfficeHourStore
 	Resolver *resolver.Resolver
 	Watcher  *watcher.Watcher
+	Drafts   *drafts.Scheduler
+
+	// TimeZone is the timezone in which office hours are interpreted.
+	TimeZone *time.Location
 
 	Catalog discovery.Discoverer
 }
@@ -0,0 +1,117 @@
+package drafts
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// pollInterval is the maximum time between two checks for due drafts.
+const pollInterval = time.Minute
+
+// Scheduler publishes drafts at their scheduled time.
+type Scheduler struct {
+	repo      repo.OfficeHourStore
+	published func()
+	trigger   chan struct{}
+}
+
+// NewScheduler returns a new scheduler. published is called whenever a draft
+// has been published.
+func NewScheduler(repo repo.OfficeHourStore, published func()) *Scheduler {
+	return &Scheduler{
+		repo:      repo,
+		published: published,
+		trigger:   make(chan struct{}, 1),
+	}
+}
+
+// Start publishes due drafts until ctx is cancelled. It should only be
+// called on the elected leader.
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			wait := s.publishDue(ctx)
+
+			// drafts scheduled on other replicas wake the scheduler using
+			// Trigger if the store supports change notifications. Check at
+			// least once a minute in case it does not.
+			select {
+			case <-time.After(wait):
+			case <-s.trigger:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Trigger causes the scheduler to check for due drafts immediately.
+func (s *Scheduler) Trigger() {
+	if s == nil {
+		return
+	}
+
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// publishDue publishes all due drafts and returns how long to wait until the
+// next draft is due, at most pollInterval.
+func (s *Scheduler) publishDue(ctx context.Context) time.Duration {
+	now := time.Now()
+
+	drafts, err := s.repo.DueDrafts(ctx, now.Add(pollInterval))
+	if err != nil {
+		slog.Error("failed to load scheduled drafts", "error", err)
+		return pollInterval
+	}
+
+	for _, d := range drafts {
+		// drafts are sorted by their publish time
+		if d.PublishAt != nil && d.PublishAt.After(now) {
+			return d.PublishAt.Sub(now)
+		}
+
+		version, err := s.repo.PublishDraft(ctx, d.ID.Hex())
+
+		var verr *repo.ValidationError
+		if errors.Is(err, repo.ErrDraftOutdated) || errors.As(err, &verr) {
+			// retrying would fail again until the draft is changed so drop
+			// the schedule instead of retrying every poll.
+			slog.Error("scheduled draft cannot be published, unscheduling it", "draft", d.ID.Hex(), "error", err)
+			s.unschedule(ctx, d.ID.Hex())
+
+			continue
+		}
+
+		if err != nil {
+			slog.Error("failed to publish scheduled draft", "draft", d.ID.Hex(), "error", err)
+			continue
+		}
+
+		slog.Info("published scheduled draft", "draft", d.ID.Hex(), "version", version.Version)
+
+		s.published()
+	}
+
+	return pollInterval
+}
+
+// unschedule clears the publish time of the draft id.
+func (s *Scheduler) unschedule(ctx context.Context, id string) {
+	draft, err := s.repo.GetDraft(ctx, id)
+	if err == nil {
+		draft.PublishAt = nil
+		err = s.repo.UpdateDraft(ctx, draft)
+	}
+
+	if err != nil {
+		slog.Error("failed to unschedule draft", "draft", id, "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package holidays
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+)
+
+type monthKey struct {
+	year  int
+	month time.Month
+}
+
+// CompositeProvider queries a primary provider and remembers the last
+// successful result for each month. If the primary provider fails, the
+// remembered result is used and, if there is none, the fallback provider is
+// queried instead. Both are returned together with an error wrapping
+// resolver.ErrDegraded so they are not cached as long as a primary result.
+type CompositeProvider struct {
+	primary  resolver.HolidayProvider
+	fallback resolver.HolidayProvider
+
+	l         sync.RWMutex
+	lastKnown map[monthKey][]string
+}
+
+func NewCompositeProvider(primary, fallback resolver.HolidayProvider) *CompositeProvider {
+	return &CompositeProvider{
+		primary:   primary,
+		fallback:  fallback,
+		lastKnown: make(map[monthKey][]string),
+	}
+}
+
+func (p *CompositeProvider) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	key := monthKey{year, month}
+
+	dates, err := p.primary.PublicHolidays(ctx, year, month)
+	if err == nil {
+		p.l.Lock()
+		p.lastKnown[key] = dates
+		p.l.Unlock()
+
+		return dates, nil
+	}
+
+	p.l.RLock()
+	dates, ok := p.lastKnown[key]
+	p.l.RUnlock()
+
+	if ok {
+		slog.Warn("failed to fetch holidays, using last known result", "year", year, "month", month, "error", err)
+
+		return dates, fmt.Errorf("%w: %w", resolver.ErrDegraded, err)
+	}
+
+	slog.Warn("failed to fetch holidays, using fallback provider", "year", year, "month", month, "error", err)
+
+	dates, fallbackErr := p.fallback.PublicHolidays(ctx, year, month)
+	if fallbackErr != nil {
+		return nil, fallbackErr
+	}
+
+	return dates, fmt.Errorf("%w: %w", resolver.ErrDegraded, err)
+}
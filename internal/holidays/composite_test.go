@@ -0,0 +1,62 @@
+package holidays
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+)
+
+type fakeProvider struct {
+	dates []string
+	err   error
+	calls int
+}
+
+func (p *fakeProvider) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	p.calls++
+
+	return p.dates, p.err
+}
+
+func TestCompositeProvider(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &fakeProvider{dates: []string{"2024-05-01"}}
+	fallback := &fakeProvider{dates: []string{"2024-05-09"}}
+
+	p := NewCompositeProvider(primary, fallback)
+
+	dates, err := p.PublicHolidays(ctx, 2024, time.May)
+	if err != nil || !slices.Equal(dates, primary.dates) {
+		t.Fatalf("expected the primary result, got %v (%v)", dates, err)
+	}
+
+	// the last known result is used if the primary provider fails
+	primary.dates, primary.err = nil, errors.New("unavailable")
+
+	dates, err = p.PublicHolidays(ctx, 2024, time.May)
+	if !errors.Is(err, resolver.ErrDegraded) || !slices.Equal(dates, []string{"2024-05-01"}) {
+		t.Fatalf("expected the last known result to be degraded, got %v (%v)", dates, err)
+	}
+
+	if fallback.calls != 0 {
+		t.Fatalf("expected the fallback to not be queried")
+	}
+
+	// without a last known result the fallback is queried
+	dates, err = p.PublicHolidays(ctx, 2024, time.June)
+	if !errors.Is(err, resolver.ErrDegraded) || !slices.Equal(dates, fallback.dates) {
+		t.Fatalf("expected the fallback result to be degraded, got %v (%v)", dates, err)
+	}
+
+	// errors of the fallback are returned
+	fallback.err = errors.New("broken")
+
+	if _, err := p.PublicHolidays(ctx, 2024, time.July); err == nil || errors.Is(err, resolver.ErrDegraded) {
+		t.Fatalf("expected the fallback error to be returned, got %v", err)
+	}
+}
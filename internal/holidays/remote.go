@@ -0,0 +1,75 @@
+package holidays
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	calendarv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/calendar/v1"
+	"github.com/tierklinik-dobersberg/apis/gen/go/tkd/calendar/v1/calendarv1connect"
+	"github.com/tierklinik-dobersberg/apis/pkg/discovery"
+	"github.com/tierklinik-dobersberg/apis/pkg/discovery/wellknown"
+)
+
+// RemoteProvider fetches public holidays from the HolidayService which is
+// looked up using the service catalog.
+type RemoteProvider struct {
+	catalog discovery.Discoverer
+
+	l   sync.Mutex
+	cli calendarv1connect.HolidayServiceClient
+}
+
+func NewRemoteProvider(catalog discovery.Discoverer) *RemoteProvider {
+	return &RemoteProvider{
+		catalog: catalog,
+	}
+}
+
+func (p *RemoteProvider) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	cli, err := p.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	holidayResponse, err := cli.GetHoliday(ctx, connect.NewRequest(&calendarv1.GetHolidayRequest{
+		Year:  uint64(year),
+		Month: uint64(month),
+	}))
+
+	if err != nil {
+		// drop the client so the next call will lookup the service again.
+		p.l.Lock()
+		p.cli = nil
+		p.l.Unlock()
+
+		return nil, fmt.Errorf("failed to fetch holidays: %w", err)
+	}
+
+	var dates []string
+	for _, holiday := range holidayResponse.Msg.Holidays {
+		if holiday.Type == calendarv1.HolidayType_PUBLIC {
+			dates = append(dates, holiday.Date)
+		}
+	}
+
+	return dates, nil
+}
+
+func (p *RemoteProvider) client(ctx context.Context) (calendarv1connect.HolidayServiceClient, error) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.cli == nil {
+		cli, err := wellknown.HolidayService.Create(ctx, p.catalog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get holiday client using service catalog: %w", err)
+		}
+
+		p.cli = cli
+	}
+
+	return p.cli, nil
+}
@@ -0,0 +1,182 @@
+package holidays
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// StaticProvider serves public holidays from a fixed list of dates.
+type StaticProvider struct {
+	// dates holds specific dates in the format YYYY-MM-DD.
+	dates map[string]struct{}
+
+	// recurring holds yearly recurring dates in the format MM-DD.
+	recurring map[string]struct{}
+}
+
+// NewStaticProvider returns a provider that serves the given dates. Each date
+// must either be formatted as YYYY-MM-DD or as MM-DD for holidays that recur
+// every year.
+func NewStaticProvider(dates []string) (*StaticProvider, error) {
+	p := &StaticProvider{
+		dates:     make(map[string]struct{}),
+		recurring: make(map[string]struct{}),
+	}
+
+	for _, d := range dates {
+		d = strings.TrimSpace(d)
+
+		if _, err := time.Parse("2006-01-02", d); err == nil {
+			p.dates[d] = struct{}{}
+			continue
+		}
+
+		if _, err := time.Parse("01-02", d); err == nil {
+			p.recurring[d] = struct{}{}
+			continue
+		}
+
+		return nil, fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD or MM-DD", d)
+	}
+
+	return p, nil
+}
+
+// LoadICSFile returns a provider that serves all events of the iCalendar file
+// at path as public holidays. Events with a yearly recurrence rule are
+// treated as recurring holidays.
+func LoadICSFile(path string) (*StaticProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open holiday file: %w", err)
+	}
+	defer f.Close()
+
+	p := &StaticProvider{
+		dates:     make(map[string]struct{}),
+		recurring: make(map[string]struct{}),
+	}
+
+	var lines []string
+
+	// unfold content lines as specified in RFC 5545 3.1
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read holiday file: %w", err)
+	}
+
+	var (
+		inEvent    bool
+		start, end time.Time
+		yearly     bool
+	)
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		// strip any property parameters
+		name, _, _ = strings.Cut(name, ";")
+
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent = true
+				start, end, yearly = time.Time{}, time.Time{}, false
+			}
+
+		case "DTSTART":
+			if inEvent {
+				start, err = parseICSDate(value)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+		case "DTEND":
+			if inEvent {
+				end, err = parseICSDate(value)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+		case "RRULE":
+			if inEvent {
+				yearly = strings.Contains(strings.ToUpper(value), "FREQ=YEARLY")
+			}
+
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+
+			inEvent = false
+
+			if start.IsZero() {
+				continue
+			}
+
+			// DTEND is exclusive for all-day events
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				if yearly {
+					p.recurring[d.Format("01-02")] = struct{}{}
+				} else {
+					p.dates[d.Format("2006-01-02")] = struct{}{}
+				}
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < len("20060102") {
+		return time.Time{}, fmt.Errorf("invalid iCalendar date %q", value)
+	}
+
+	t, err := time.Parse("20060102", value[:len("20060102")])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid iCalendar date %q: %w", value, err)
+	}
+
+	return t, nil
+}
+
+func (p *StaticProvider) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	var dates []string
+
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	for d := first; d.Month() == month; d = d.AddDate(0, 0, 1) {
+		_, isDate := p.dates[d.Format("2006-01-02")]
+		_, isRecurring := p.recurring[d.Format("01-02")]
+
+		if isDate || isRecurring {
+			dates = append(dates, d.Format("2006-01-02"))
+		}
+	}
+
+	return dates, nil
+}
@@ -0,0 +1,113 @@
+package holidays
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStaticProvider(t *testing.T) {
+	p, err := NewStaticProvider([]string{"2024-05-09", " 12-25 ", "01-01"})
+	if err != nil {
+		t.Fatalf("failed to create provider: %s", err)
+	}
+
+	cases := []struct {
+		year     int
+		month    time.Month
+		expected []string
+	}{
+		{2024, time.May, []string{"2024-05-09"}},
+		{2025, time.May, nil},
+		{2024, time.December, []string{"2024-12-25"}},
+		{2030, time.January, []string{"2030-01-01"}},
+		{2024, time.June, nil},
+	}
+
+	for _, c := range cases {
+		dates, err := p.PublicHolidays(context.Background(), c.year, c.month)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if !slices.Equal(dates, c.expected) {
+			t.Errorf("%d-%02d: expected %v but got %v", c.year, c.month, c.expected, dates)
+		}
+	}
+
+	for _, invalid := range []string{"2024-13-01", "24-12-24", "christmas"} {
+		if _, err := NewStaticProvider([]string{invalid}); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestLoadICSFile(t *testing.T) {
+	content := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Christmas\r\n" +
+		"DTSTART;VALUE=DATE:20241224\r\n" +
+		"DTEND;VALUE=DATE:20241227\r\n" +
+		"RRULE:FREQ=YEARLY\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Ascension\r\n" +
+		"DTSTART;VALUE=DATE:2024\r\n" +
+		" 0509\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:Timed event\r\n" +
+		"DTSTART:20241231T100000Z\r\n" +
+		"DTEND:20241231T120000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	path := filepath.Join(t.TempDir(), "holidays.ics")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	p, err := LoadICSFile(path)
+	if err != nil {
+		t.Fatalf("failed to load file: %s", err)
+	}
+
+	cases := []struct {
+		year     int
+		month    time.Month
+		expected []string
+	}{
+		// DTEND is exclusive and the rule makes the event recur yearly
+		{2030, time.December, []string{"2030-12-24", "2030-12-25", "2030-12-26"}},
+		// folded content lines are unfolded
+		{2024, time.May, []string{"2024-05-09"}},
+		{2025, time.May, nil},
+	}
+
+	for _, c := range cases {
+		dates, err := p.PublicHolidays(context.Background(), c.year, c.month)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if !slices.Equal(dates, c.expected) {
+			t.Errorf("%d-%02d: expected %v but got %v", c.year, c.month, c.expected, dates)
+		}
+	}
+
+	dates, _ := p.PublicHolidays(context.Background(), 2024, time.December)
+	if !slices.Contains(dates, "2024-12-31") {
+		t.Errorf("expected the timed event to mark its day as a holiday, got %v", dates)
+	}
+
+	if err := os.WriteFile(path, []byte("BEGIN:VEVENT\nDTSTART:foo\nEND:VEVENT\n"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	if _, err := LoadICSFile(path); err == nil {
+		t.Errorf("expected an invalid date to be rejected")
+	}
+}
@@ -0,0 +1,129 @@
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// Elector uses a lease document to elect a single leader between all
+// replicas of the service. The leader renews its lease periodically. If it
+// fails to do so, another replica takes over after the lease expired.
+type Elector struct {
+	repo repo.OfficeHourStore
+	name string
+	id   string
+	ttl  time.Duration
+}
+
+// New returns a new elector campaigning for the lease name. A standby
+// replica takes over at most ttl (plus one renew interval) after the leader
+// stopped renewing the lease. The ttl must be positive.
+func New(repo repo.OfficeHourStore, name string, ttl time.Duration) (*Elector, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("invalid lease ttl %s for %q, must be positive", ttl, name)
+	}
+
+	hostname, _ := os.Hostname()
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+
+	return &Elector{
+		repo: repo,
+		name: name,
+		id:   hostname + "-" + hex.EncodeToString(suffix),
+		ttl:  ttl,
+	}, nil
+}
+
+// Run campaigns for leadership in the background until ctx is cancelled.
+// Whenever this replica becomes the leader, onElected is called with a
+// context that is cancelled as soon as the leadership is lost.
+func (e *Elector) Run(ctx context.Context, onElected func(ctx context.Context)) {
+	go func() {
+		for {
+			if !e.campaign(ctx) {
+				return
+			}
+
+			slog.Info("elected as leader", "lease", e.name, "id", e.id)
+
+			leaderCtx, cancel := context.WithCancel(ctx)
+			onElected(leaderCtx)
+
+			e.hold(ctx)
+			cancel()
+
+			if ctx.Err() != nil {
+				// release the lease so a standby can take over immediately.
+				releaseCtx, releaseCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				if err := e.repo.ReleaseLease(releaseCtx, e.name, e.id); err != nil {
+					slog.Error("failed to release lease", "lease", e.name, "error", err)
+				}
+				releaseCancel()
+
+				return
+			}
+
+			slog.Warn("lost leadership", "lease", e.name, "id", e.id)
+		}
+	}()
+}
+
+// campaign blocks until the lease has been acquired. It returns false if
+// ctx has been cancelled before.
+func (e *Elector) campaign(ctx context.Context) bool {
+	for {
+		if e.acquire(ctx) {
+			return true
+		}
+
+		select {
+		case <-time.After(e.interval()):
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// hold renews the lease until it is lost or ctx is cancelled.
+func (e *Elector) hold(ctx context.Context) {
+	for {
+		select {
+		case <-time.After(e.interval()):
+		case <-ctx.Done():
+			return
+		}
+
+		// If the lease could not be renewed we cannot be sure that we still
+		// hold it so we must step down.
+		if !e.acquire(ctx) {
+			return
+		}
+	}
+}
+
+func (e *Elector) acquire(ctx context.Context) bool {
+	ok, err := e.repo.AcquireLease(ctx, e.name, e.id, e.ttl)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to acquire lease", "lease", e.name, "error", err)
+		}
+
+		return false
+	}
+
+	return ok
+}
+
+// interval returns the interval at which the lease is acquired or renewed.
+func (e *Elector) interval() time.Duration {
+	return e.ttl / 3
+}
@@ -0,0 +1,165 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+const testTTL = 60 * time.Millisecond
+
+// flakyStore fails to acquire leases for a holder after fail has been
+// called, simulating a replica that lost the connection to the database.
+type flakyStore struct {
+	repo.OfficeHourStore
+
+	lock    sync.Mutex
+	failing map[string]bool
+}
+
+func newFlakyStore() *flakyStore {
+	return &flakyStore{
+		OfficeHourStore: repo.NewMemoryStore(),
+		failing:         make(map[string]bool),
+	}
+}
+
+func (s *flakyStore) fail(holder string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.failing[holder] = true
+}
+
+func (s *flakyStore) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	failing := s.failing[holder]
+	s.lock.Unlock()
+
+	if failing {
+		return false, errors.New("connection lost")
+	}
+
+	return s.OfficeHourStore.AcquireLease(ctx, name, holder, ttl)
+}
+
+// leaderEvent is sent whenever an elector is elected or steps down.
+type leaderEvent struct {
+	id      string
+	elected bool
+}
+
+func run(ctx context.Context, t *testing.T, store repo.OfficeHourStore, events chan<- leaderEvent) *Elector {
+	t.Helper()
+
+	e, err := New(store, "test", testTTL)
+	if err != nil {
+		t.Fatalf("failed to create elector: %s", err)
+	}
+
+	e.Run(ctx, func(ctx context.Context) {
+		events <- leaderEvent{id: e.id, elected: true}
+
+		go func() {
+			<-ctx.Done()
+			events <- leaderEvent{id: e.id}
+		}()
+	})
+
+	return e
+}
+
+func next(t *testing.T, events <-chan leaderEvent) leaderEvent {
+	t.Helper()
+
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for a leadership change")
+	}
+
+	return leaderEvent{}
+}
+
+func TestNewRejectsInvalidTTL(t *testing.T) {
+	if _, err := New(newFlakyStore(), "test", 0); err == nil {
+		t.Fatalf("expected an error for a zero ttl")
+	}
+}
+
+func TestElectorTakeover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := newFlakyStore()
+	events := make(chan leaderEvent, 10)
+
+	first := run(ctx, t, store, events)
+
+	if e := next(t, events); e.id != first.id || !e.elected {
+		t.Fatalf("expected the first elector to be elected, got %+v", e)
+	}
+
+	second := run(ctx, t, store, events)
+
+	// the leader renews its lease so the second elector must not take over
+	// while the leader is healthy.
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected leadership change while the leader renews its lease: %+v", e)
+	case <-time.After(3 * testTTL):
+	}
+
+	store.fail(first.id)
+
+	if e := next(t, events); e.id != first.id || e.elected {
+		t.Fatalf("expected the leader to step down after failing to renew its lease, got %+v", e)
+	}
+
+	if e := next(t, events); e.id != second.id || !e.elected {
+		t.Fatalf("expected the second elector to take over after the lease expired, got %+v", e)
+	}
+}
+
+func TestElectorReleasesLease(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	store := newFlakyStore()
+	events := make(chan leaderEvent, 10)
+
+	first := run(ctx, t, store, events)
+
+	if e := next(t, events); !e.elected {
+		t.Fatalf("expected the elector to be elected, got %+v", e)
+	}
+
+	cancel()
+
+	if e := next(t, events); e.elected {
+		t.Fatalf("expected the elector to step down, got %+v", e)
+	}
+
+	// the lease is released asynchronously after stepping down
+	deadline := time.Now().Add(time.Second)
+	for {
+		ok, err := store.AcquireLease(context.Background(), "test", "other", time.Hour)
+		if err != nil {
+			t.Fatalf("failed to acquire lease: %s", err)
+		}
+
+		if ok {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the lease of %s to be released", first.id)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Actor describes the user that performs a mutation.
+type Actor struct {
+	ID          string `bson:"id,omitempty" json:"id,omitempty"`
+	Username    string `bson:"username,omitempty" json:"username,omitempty"`
+	DisplayName string `bson:"displayName,omitempty" json:"displayName,omitempty"`
+}
+
+type actorContextKey struct{}
+
+// WithActor returns a new context that carries actor. Mutations performed
+// using the returned context are recorded in the audit log with actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFrom returns the actor associated with ctx.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorContextKey{}).(Actor)
+
+	return actor
+}
+
+// Kinds of mutations recorded in the audit log.
+const (
+	AuditActionUpsert  = "upsert"
+	AuditActionDelete  = "delete"
+	AuditActionRestore = "restore"
+)
+
+// AuditEntry records a single mutation of an office hour.
+type AuditEntry struct {
+	ID primitive.ObjectID `bson:"_id" json:"id"`
+
+	// OfficeHour is the name of the office hour that has been changed.
+	OfficeHour string `bson:"officeHour" json:"officeHour"`
+
+	// Action is the kind of mutation.
+	Action string `bson:"action" json:"action"`
+
+	// Previous is the office hour before the mutation. It is nil if the
+	// office hour has been created.
+	Previous *OfficeHourModel `bson:"previous,omitempty" json:"previous,omitempty"`
+
+	// Current is the office hour after the mutation. It is nil if the
+	// office hour has been deleted.
+	Current *OfficeHourModel `bson:"current,omitempty" json:"current,omitempty"`
+
+	Actor     Actor     `bson:"actor" json:"actor"`
+	Timestamp time.Time `bson:"timestamp" json:"timestamp"`
+}
+
+// ListAuditEntries returns the audit trail of the office hour identified by
+// name, most recent entries first.
+func (r *Repo) ListAuditEntries(ctx context.Context, name string) ([]AuditEntry, error) {
+	res, err := r.audit.Find(ctx, bson.M{
+		"officeHour": name,
+	}, options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []AuditEntry
+	if err := res.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// recordAudit stores an audit entry for a mutation of an office hour.
+func (r *Repo) recordAudit(ctx context.Context, action string, name string, previous, current *OfficeHourModel) error {
+	_, err := r.audit.InsertOne(ctx, AuditEntry{
+		ID:         primitive.NewObjectID(),
+		OfficeHour: name,
+		Action:     action,
+		Previous:   previous,
+		Current:    current,
+		Actor:      ActorFrom(ctx),
+		Timestamp:  time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ChangeWatcher is implemented by stores that can be modified by other
+// processes and therefore need to notify about changes.
+type ChangeWatcher interface {
+	// WatchChanges calls onChange whenever office hours or overrides
+	// change, onDraftChange whenever a draft is created, scheduled or
+	// modified and onEvent whenever an event is enqueued until ctx is
+	// cancelled.
+	WatchChanges(ctx context.Context, onChange func(), onDraftChange func(), onEvent func())
+}
+
+// changeStreamRetry is the delay before the change stream is re-opened after
+// it failed.
+const changeStreamRetry = 30 * time.Second
+
+// changeCache is an in-memory snapshot of all office hours and overrides
+// that is kept up to date using a change stream.
+type changeCache struct {
+	lock sync.RWMutex
+
+	// valid is false while the snapshot might be outdated.
+	valid bool
+
+	// generation is incremented whenever the snapshot is invalidated so
+	// a reload that raced with a local change does not mark the snapshot
+	// as valid.
+	generation uint64
+
+	hours     Schedule
+	overrides map[string]Override
+}
+
+// WatchChanges opens a change stream on the office-hour, override and draft
+// collections. While the change stream is healthy, office hours and
+// overrides are served from an in-memory snapshot and onChange is called on
+// every change, including changes made by other replicas or directly in the
+// database. Changes to drafts only call onDraftChange so drafts scheduled on
+// another replica are published on time by the leader. Likewise, onEvent is
+// called whenever an event is inserted into the outbox so events enqueued
+// by another replica, for example when an override is set, are delivered by
+// the leader immediately.
+//
+// Note that change streams require MongoDB to run as a replica set. If the
+// change stream cannot be opened, all lookups are served from the database.
+func (r *Repo) WatchChanges(ctx context.Context, onChange func(), onDraftChange func(), onEvent func()) {
+	go func() {
+		for {
+			err := r.watchChanges(ctx, onChange, onDraftChange, onEvent)
+
+			r.invalidateCache()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			slog.Warn("office-hour change stream failed, retrying", "retry", changeStreamRetry.String(), "error", err)
+
+			select {
+			case <-time.After(changeStreamRetry):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (r *Repo) watchChanges(ctx context.Context, onChange func(), onDraftChange func(), onEvent func()) error {
+	// Updates of outbox entries are caused by delivery attempts so only
+	// watch for new events.
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"$or": bson.A{
+				bson.M{"ns.coll": bson.M{
+					"$in": bson.A{r.col.Name(), r.overrides.Name(), r.drafts.Name()},
+				}},
+				bson.M{
+					"ns.coll":       r.outbox.Name(),
+					"operationType": "insert",
+				},
+			},
+		}}},
+	}
+
+	// open the stream before loading the snapshot so no change is missed
+	stream, err := r.col.Database().Watch(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	if err := r.reloadCache(ctx); err != nil {
+		return err
+	}
+
+	// we might have missed changes while the stream was closed
+	onChange()
+	onDraftChange()
+	onEvent()
+
+	for stream.Next(ctx) {
+		// Coalesce all changes that are already available so a bulk write
+		// only reloads the snapshot once.
+		var hoursChanged, draftsChanged, eventsEnqueued bool
+		for {
+			var event struct {
+				NS struct {
+					Coll string `bson:"coll"`
+				} `bson:"ns"`
+			}
+			if err := stream.Decode(&event); err != nil {
+				return fmt.Errorf("failed to decode change event: %w", err)
+			}
+
+			switch event.NS.Coll {
+			case r.drafts.Name():
+				draftsChanged = true
+
+			case r.outbox.Name():
+				eventsEnqueued = true
+
+			default:
+				hoursChanged = true
+			}
+
+			if !stream.TryNext(ctx) {
+				break
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			return err
+		}
+
+		if draftsChanged {
+			onDraftChange()
+		}
+
+		if eventsEnqueued {
+			onEvent()
+		}
+
+		if hoursChanged {
+			if err := r.reloadCache(ctx); err != nil {
+				return err
+			}
+
+			onChange()
+		}
+	}
+
+	return stream.Err()
+}
+
+// reloadCache loads all office hours and overrides into the snapshot.
+func (r *Repo) reloadCache(ctx context.Context) error {
+	r.cache.lock.RLock()
+	generation := r.cache.generation
+	r.cache.lock.RUnlock()
+
+	hours, err := r.findModels(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to load office hours: %w", err)
+	}
+
+	res, err := r.overrides.Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("failed to load overrides: %w", err)
+	}
+
+	var overrides []Override
+	if err := res.All(ctx, &overrides); err != nil {
+		return fmt.Errorf("failed to decode overrides: %w", err)
+	}
+
+	r.cache.lock.Lock()
+	defer r.cache.lock.Unlock()
+
+	r.cache.hours = hours
+	r.cache.overrides = make(map[string]Override, len(overrides))
+	for _, o := range overrides {
+		r.cache.overrides[o.Location] = o
+	}
+
+	// Only mark the snapshot as valid if there was no local change in the
+	// meantime. Otherwise, the change stream will trigger another reload.
+	r.cache.valid = r.cache.generation == generation
+
+	return nil
+}
+
+// invalidateCache marks the snapshot as outdated so lookups are served from
+// the database until it has been reloaded.
+func (r *Repo) invalidateCache() {
+	r.cache.lock.Lock()
+	defer r.cache.lock.Unlock()
+
+	r.cache.valid = false
+	r.cache.generation++
+}
+
+// cachedSchedule returns all office hours from the snapshot. The second
+// return value is false if the snapshot is not available.
+func (r *Repo) cachedSchedule() (Schedule, bool) {
+	r.cache.lock.RLock()
+	defer r.cache.lock.RUnlock()
+
+	return r.cache.hours, r.cache.valid
+}
+
+// cachedOverride returns the override of location from the snapshot. The
+// second return value is false if the snapshot is not available.
+func (r *Repo) cachedOverride(location string) (*Override, bool) {
+	r.cache.lock.RLock()
+	defer r.cache.lock.RUnlock()
+
+	if !r.cache.valid {
+		return nil, false
+	}
+
+	o, ok := r.cache.overrides[location]
+	if !ok {
+		return nil, true
+	}
+
+	return &o, true
+}
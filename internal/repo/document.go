@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Supported formats of schedule documents.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// ScheduleDocument is the human-editable representation of the whole
+// schedule used for import and export.
+type ScheduleDocument struct {
+	OfficeHours []OfficeHourModel `json:"officeHours"`
+}
+
+// Encode returns doc encoded in format. An empty format defaults to YAML.
+func (doc *ScheduleDocument) Encode(format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+
+	case FormatYAML, "":
+		return yaml.Marshal(doc)
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// DecodeScheduleDocument decodes a schedule document from data. An empty
+// format defaults to YAML. Since YAML is a superset of JSON, YAML can be
+// used to decode JSON documents as well.
+//
+// Office hours without a name are assigned one derived from their content
+// (see derivedID) so importing the same document twice does not replace
+// them again.
+func DecodeScheduleDocument(data []byte, format string) (*ScheduleDocument, error) {
+	var doc ScheduleDocument
+
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid schedule document: %w", err)
+		}
+
+	case FormatYAML, "":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("invalid schedule document: %w", err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	// assign a name to all new office hours
+	for idx := range doc.OfficeHours {
+		if doc.OfficeHours[idx].ID.IsZero() {
+			id, err := derivedID(doc.OfficeHours[idx])
+			if err != nil {
+				return nil, err
+			}
+
+			doc.OfficeHours[idx].ID = id
+		}
+	}
+
+	return &doc, nil
+}
+
+// derivedID returns a stable name for the unnamed office hour m by hashing
+// its JSON representation. Unnamed office hours with the same content get
+// the same name and are reported as duplicates by Validate.
+func derivedID(m OfficeHourModel) (primitive.ObjectID, error) {
+	blob, err := json.Marshal(m)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to derive name: %w", err)
+	}
+
+	var id primitive.ObjectID
+	sum := sha256.Sum256(blob)
+	copy(id[:], sum[:])
+
+	return id, nil
+}
+
+// Validate checks all office hours of doc for semantic errors and conflicts
+// between each other. Field paths of violations are prefixed with the index
+// of the office hour.
+func (doc *ScheduleDocument) Validate() error {
+	verr := new(ValidationError)
+
+	names := make(map[primitive.ObjectID]int, len(doc.OfficeHours))
+	for idx, m := range doc.OfficeHours {
+		if first, ok := names[m.ID]; ok {
+			verr.add(fmt.Sprintf("officeHours[%d].name", idx), "duplicate name, already used by officeHours[%d]", first)
+		} else {
+			names[m.ID] = idx
+		}
+	}
+
+	for idx := range doc.OfficeHours {
+		err := doc.OfficeHours[idx].ValidateWith(doc.OfficeHours)
+		if err == nil {
+			continue
+		}
+
+		for _, v := range err.(*ValidationError).Violations {
+			verr.add(fmt.Sprintf("officeHours[%d].%s", idx, v.Field), "%s", v.Description)
+		}
+	}
+
+	if len(verr.Violations) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// ExportSchedule returns the live schedule as a schedule document.
+func (r *Repo) ExportSchedule(ctx context.Context) (*ScheduleDocument, error) {
+	hours, err := r.ListOfficeHours(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScheduleDocument{
+		OfficeHours: Schedule(hours).Sorted(),
+	}, nil
+}
+
+// ImportSchedule replaces the live schedule with the office hours of doc and
+// returns the applied changes. If dryRun is set, the changes are only
+// calculated but not applied. Otherwise, all changes, their audit entries
+// and the new schedule version are stored in a single transaction.
+//
+// doc is expected to be validated already.
+func (r *Repo) ImportSchedule(ctx context.Context, doc *ScheduleDocument, dryRun bool) (ScheduleDiff, *ScheduleVersion, error) {
+	if dryRun {
+		current, err := r.ListOfficeHours(ctx, "")
+		if err != nil {
+			return ScheduleDiff{}, nil, err
+		}
+
+		return DiffSchedules(current, doc.OfficeHours), nil, nil
+	}
+
+	var (
+		diff    ScheduleDiff
+		version *ScheduleVersion
+	)
+
+	err := r.withTransaction(ctx, func(ctx context.Context) error {
+		current, err := r.ListOfficeHours(ctx, "")
+		if err != nil {
+			return err
+		}
+
+		diff = DiffSchedules(current, doc.OfficeHours)
+		if err := r.applyDiff(ctx, diff); err != nil {
+			return err
+		}
+
+		version, err = r.snapshot(ctx, "import")
+
+		return err
+	})
+
+	r.invalidateCache()
+
+	if err != nil {
+		return diff, nil, err
+	}
+
+	return diff, version, nil
+}
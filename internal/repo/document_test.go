@@ -0,0 +1,285 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeScheduleDocumentDerivesStableNames(t *testing.T) {
+	data := []byte(`
+officeHours:
+  - dayOfWeek: 1
+    timeRanges:
+      - start: {hours: 8}
+        end: {hours: 12}
+  - dayOfWeek: 2
+    timeRanges:
+      - start: {hours: 8}
+        end: {hours: 12}
+`)
+
+	first, err := DecodeScheduleDocument(data, FormatYAML)
+	if err != nil {
+		t.Fatalf("failed to decode document: %s", err)
+	}
+
+	second, err := DecodeScheduleDocument(data, FormatYAML)
+	if err != nil {
+		t.Fatalf("failed to decode document: %s", err)
+	}
+
+	for idx := range first.OfficeHours {
+		if first.OfficeHours[idx].ID.IsZero() {
+			t.Errorf("expected officeHours[%d] to be named", idx)
+		}
+
+		if first.OfficeHours[idx].ID != second.OfficeHours[idx].ID {
+			t.Errorf("expected officeHours[%d] to get the same name when decoded twice", idx)
+		}
+	}
+
+	if first.OfficeHours[0].ID == first.OfficeHours[1].ID {
+		t.Errorf("expected different office hours to get different names")
+	}
+
+	if diff := DiffSchedules(first.OfficeHours, second.OfficeHours); !diff.IsEmpty() {
+		t.Errorf("expected re-importing the document to be a no-op, got %+v", diff)
+	}
+}
+
+func TestScheduleDocumentRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := NewMemoryStore()
+
+	for _, m := range []OfficeHourModel{
+		{
+			DayOfWeek: time.Monday,
+			TimeRanges: []DayTimeRange{
+				{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+				{Start: DayTime{Hours: 14}, End: DayTime{Hours: 18}, Mode: ModeAppointmentsOnly},
+			},
+		},
+		{
+			Location:  "surgery",
+			DayOfWeek: time.Monday,
+			TimeRanges: []DayTimeRange{
+				{Start: DayTime{Hours: 7, Minutes: 30}, End: DayTime{Hours: 11}},
+			},
+		},
+		{
+			Date: "12-24",
+		},
+		{
+			DateRange: &DateRange{From: "07-15", To: "08-15", Yearly: true, Weekdays: []time.Weekday{time.Saturday}},
+			TimeRanges: []DayTimeRange{
+				{Start: DayTime{Hours: 9}, End: DayTime{Hours: 11}, Mode: ModeEmergencyOnly},
+			},
+		},
+	} {
+		if _, err := source.UpsertOfficeHours(ctx, &m); err != nil {
+			t.Fatalf("failed to upsert office hour: %s", err)
+		}
+	}
+
+	exported, err := source.ExportSchedule(ctx)
+	if err != nil {
+		t.Fatalf("failed to export schedule: %s", err)
+	}
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			data, err := exported.Encode(format)
+			if err != nil {
+				t.Fatalf("failed to encode document: %s", err)
+			}
+
+			doc, err := DecodeScheduleDocument(data, format)
+			if err != nil {
+				t.Fatalf("failed to decode document: %s", err)
+			}
+
+			if err := doc.Validate(); err != nil {
+				t.Fatalf("expected the exported document to be valid: %s", err)
+			}
+
+			// importing the document into the source is a no-op
+			diff, _, err := source.ImportSchedule(ctx, doc, true)
+			if err != nil {
+				t.Fatalf("failed to import schedule: %s", err)
+			}
+
+			if !diff.IsEmpty() {
+				t.Errorf("expected re-importing the export to be a no-op, got %+v", diff)
+			}
+
+			target := NewMemoryStore()
+			if _, _, err := target.ImportSchedule(ctx, doc, false); err != nil {
+				t.Fatalf("failed to import schedule: %s", err)
+			}
+
+			imported, err := target.ExportSchedule(ctx)
+			if err != nil {
+				t.Fatalf("failed to export schedule: %s", err)
+			}
+
+			if diff := DiffSchedules(exported.OfficeHours, imported.OfficeHours); !diff.IsEmpty() {
+				t.Errorf("expected the imported schedule to match the export, got %+v", diff)
+			}
+		})
+	}
+}
+
+func TestScheduleDocumentValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		data  string
+		field string
+	}{
+		{
+			name: "conflicting office hours",
+			data: `
+officeHours:
+  - dayOfWeek: 1
+    timeRanges:
+      - start: {hours: 8}
+        end: {hours: 12}
+  - dayOfWeek: 1
+    timeRanges:
+      - start: {hours: 14}
+        end: {hours: 18}
+`,
+			field: "officeHours[0].dayOfWeek",
+		},
+		{
+			// unnamed office hours with the same content get the same name
+			name: "duplicate names",
+			data: `
+officeHours:
+  - date: 12-24
+  - date: 12-24
+`,
+			field: "officeHours[1].name",
+		},
+		{
+			name: "invalid date",
+			data: `
+officeHours:
+  - date: 13-45
+`,
+			field: "officeHours[0].date",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := DecodeScheduleDocument([]byte(c.data), FormatYAML)
+			if err != nil {
+				t.Fatalf("failed to decode document: %s", err)
+			}
+
+			err = doc.Validate()
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected a validation error but got %v", err)
+			}
+
+			found := false
+			for _, v := range verr.Violations {
+				if strings.HasPrefix(v.Field, c.field) {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Errorf("expected a violation of %s, got %+v", c.field, verr.Violations)
+			}
+		})
+	}
+}
+
+func TestImportScheduleIsAtomic(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "data")
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+
+	store, err := NewFileStore(filepath.Join(dir, "store.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create file store: %s", err)
+	}
+
+	monday, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{Date: "12-24"}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	before, err := store.ExportSchedule(ctx)
+	if err != nil {
+		t.Fatalf("failed to export schedule: %s", err)
+	}
+
+	audit := len(store.state.Audit)
+	versions := len(store.state.Versions)
+
+	// the document changes the monday office hour, removes the one on
+	// december 24th and adds one on december 31st.
+	changed := *monday
+	changed.TimeRanges = []DayTimeRange{{Start: DayTime{Hours: 9}, End: DayTime{Hours: 12}}}
+
+	doc := &ScheduleDocument{
+		OfficeHours: []OfficeHourModel{changed, {Date: "12-31"}},
+	}
+
+	doc.OfficeHours[1].ID, err = derivedID(doc.OfficeHours[1])
+	if err != nil {
+		t.Fatalf("failed to derive name: %s", err)
+	}
+
+	diff, _, err := store.ImportSchedule(ctx, doc, true)
+	if err != nil {
+		t.Fatalf("failed to preview import: %s", err)
+	}
+
+	if len(diff.Added) != 1 || len(diff.Removed) != 1 || len(diff.Changed) != 1 {
+		t.Fatalf("expected one added, removed and changed office hour, got %+v", diff)
+	}
+
+	// the store file can no longer be written
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("failed to remove directory: %s", err)
+	}
+
+	if _, _, err := store.ImportSchedule(ctx, doc, false); err == nil {
+		t.Fatalf("expected the import to fail")
+	}
+
+	after, err := store.ExportSchedule(ctx)
+	if err != nil {
+		t.Fatalf("failed to export schedule: %s", err)
+	}
+
+	if diff := DiffSchedules(before.OfficeHours, after.OfficeHours); !diff.IsEmpty() {
+		t.Errorf("expected none of the changes to be applied, got %+v", diff)
+	}
+
+	if len(store.state.Audit) != audit || len(store.state.Versions) != versions {
+		t.Errorf("expected no audit entries or versions to be recorded for the failed import")
+	}
+}
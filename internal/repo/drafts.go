@@ -0,0 +1,324 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrDraftNotFound is returned if a draft does not exist.
+	ErrDraftNotFound = errors.New("draft not found")
+
+	// ErrDraftPublished is returned when modifying a draft that has already
+	// been published.
+	ErrDraftPublished = errors.New("draft has already been published")
+
+	// ErrDraftOutdated is returned when publishing a draft after the live
+	// schedule has been changed since the draft was created. It wraps
+	// ErrRevisionMismatch.
+	ErrDraftOutdated = fmt.Errorf("live schedule has been modified since the draft was created: %w", ErrRevisionMismatch)
+)
+
+// Draft is a full schedule that can be edited and previewed without
+// affecting the live office hours.
+type Draft struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+
+	// OfficeHours holds all office hours of the draft. It is not set when
+	// listing drafts.
+	OfficeHours []OfficeHourModel `bson:"officeHours" json:"officeHours,omitempty"`
+
+	// BaseRevisions holds the revision of each live office hour, keyed by
+	// its hex ID, at the time the draft was created. Publishing fails with
+	// ErrDraftOutdated if the live schedule no longer matches. It is nil for
+	// drafts created before base revisions were recorded.
+	BaseRevisions map[string]int64 `bson:"baseRevisions,omitempty" json:"baseRevisions"`
+
+	// Revision is incremented whenever the draft is updated. UpdateDraft
+	// returns ErrRevisionMismatch if the stored draft has a different
+	// revision.
+	Revision int64 `bson:"revision" json:"revision"`
+
+	Actor     Actor     `bson:"actor" json:"actor"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+
+	// PublishAt is set if the draft is scheduled to be published.
+	PublishAt *time.Time `bson:"publishAt,omitempty" json:"publishAt,omitempty"`
+
+	// PublishedAt is set once the draft has been published.
+	PublishedAt *time.Time `bson:"publishedAt,omitempty" json:"publishedAt,omitempty"`
+}
+
+// Schedule returns the office hours of d as a Schedule.
+func (d *Draft) Schedule() Schedule {
+	return Schedule(d.OfficeHours)
+}
+
+// baseRevisions returns the revisions of the live office hours for
+// Draft.BaseRevisions.
+func baseRevisions(live []OfficeHourModel) map[string]int64 {
+	revisions := make(map[string]int64, len(live))
+	for _, m := range live {
+		revisions[m.ID.Hex()] = m.Revision
+	}
+
+	return revisions
+}
+
+// checkBase returns ErrDraftOutdated if an office hour of the live schedule
+// has been created, modified or deleted since d was created. Publishing the
+// draft would otherwise silently revert those changes.
+func (d *Draft) checkBase(live []OfficeHourModel) error {
+	if d.BaseRevisions == nil {
+		return nil
+	}
+
+	if len(live) != len(d.BaseRevisions) {
+		return ErrDraftOutdated
+	}
+
+	for _, m := range live {
+		revision, ok := d.BaseRevisions[m.ID.Hex()]
+		if !ok || revision != m.Revision {
+			return ErrDraftOutdated
+		}
+	}
+
+	return nil
+}
+
+// CreateDraft creates a new draft that starts as a copy of the live
+// schedule.
+func (r *Repo) CreateDraft(ctx context.Context, description string) (*Draft, error) {
+	hours, err := r.ListOfficeHours(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	draft := &Draft{
+		ID:            primitive.NewObjectID(),
+		Description:   description,
+		OfficeHours:   hours,
+		BaseRevisions: baseRevisions(hours),
+		Revision:      1,
+		Actor:         ActorFrom(ctx),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	if _, err := r.drafts.InsertOne(ctx, draft); err != nil {
+		return nil, fmt.Errorf("failed to create draft: %w", err)
+	}
+
+	return draft, nil
+}
+
+// GetDraft returns the draft identified by id.
+func (r *Repo) GetDraft(ctx context.Context, id string) (*Draft, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid draft id: %w", err)
+	}
+
+	var draft Draft
+	if err := r.drafts.FindOne(ctx, bson.M{"_id": oid}).Decode(&draft); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrDraftNotFound
+		}
+
+		return nil, err
+	}
+
+	return &draft, nil
+}
+
+// ListDrafts returns all drafts without their office hours.
+func (r *Repo) ListDrafts(ctx context.Context) ([]Draft, error) {
+	res, err := r.drafts.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetProjection(bson.M{"officeHours": 0}))
+	if err != nil {
+		return nil, err
+	}
+
+	var drafts []Draft
+	if err := res.All(ctx, &drafts); err != nil {
+		return nil, fmt.Errorf("failed to decode drafts: %w", err)
+	}
+
+	return drafts, nil
+}
+
+// UpdateDraft replaces the office hours and the publishing schedule of an
+// unpublished draft. The stored draft must still have draft.Revision or
+// ErrRevisionMismatch is returned.
+func (r *Repo) UpdateDraft(ctx context.Context, draft *Draft) error {
+	// drafts created before revisions were introduced do not have a
+	// revision field.
+	var revision any = draft.Revision
+	if draft.Revision == 0 {
+		revision = bson.M{"$exists": false}
+	}
+
+	updatedAt := time.Now()
+
+	res, err := r.drafts.UpdateOne(ctx, bson.M{
+		"_id":         draft.ID,
+		"publishedAt": bson.M{"$exists": false},
+		"revision":    revision,
+	}, bson.M{
+		"$set": bson.M{
+			"officeHours": draft.OfficeHours,
+			"publishAt":   draft.PublishAt,
+			"updatedAt":   updatedAt,
+			"revision":    draft.Revision + 1,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update draft: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return r.draftError(ctx, draft.ID)
+	}
+
+	draft.UpdatedAt = updatedAt
+	draft.Revision++
+
+	return nil
+}
+
+// DeleteDraft deletes the draft identified by id.
+func (r *Repo) DeleteDraft(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid draft id: %w", err)
+	}
+
+	res, err := r.drafts.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return ErrDraftNotFound
+	}
+
+	return nil
+}
+
+// PublishDraft replaces the live schedule with the office hours of the
+// draft identified by id. All changes are applied using a single bulk
+// write and stored as a new schedule version. If the live schedule has been
+// changed since the draft was created, ErrDraftOutdated is returned and the
+// draft stays unpublished. A *ValidationError is returned if the draft
+// contains conflicting office hours.
+func (r *Repo) PublishDraft(ctx context.Context, id string) (*ScheduleVersion, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid draft id: %w", err)
+	}
+
+	var result *ScheduleVersion
+
+	// Claiming the draft and applying it happens in one transaction so the
+	// draft is either published exactly once, for example when a scheduled
+	// publish races with another replica, or stays unpublished if applying
+	// it fails.
+	err = r.withTransaction(ctx, func(ctx context.Context) error {
+		var draft Draft
+		if err := r.drafts.FindOneAndUpdate(ctx, bson.M{
+			"_id":         oid,
+			"publishedAt": bson.M{"$exists": false},
+		}, bson.M{
+			"$set": bson.M{"publishedAt": time.Now()},
+		}).Decode(&draft); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return r.draftError(ctx, oid)
+			}
+
+			return fmt.Errorf("failed to mark draft as published: %w", err)
+		}
+
+		current, err := r.ListOfficeHours(ctx, "")
+		if err != nil {
+			return err
+		}
+
+		if err := draft.checkBase(current); err != nil {
+			return err
+		}
+
+		// publishing replaces the whole live schedule with the draft
+		if err := draft.Schedule().Validate(); err != nil {
+			return err
+		}
+
+		if err := r.applyDiff(ctx, DiffSchedules(current, draft.OfficeHours)); err != nil {
+			return err
+		}
+
+		result, err = r.snapshot(ctx, "publish draft "+id)
+
+		return err
+	})
+
+	r.invalidateCache()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DueDrafts returns all unpublished drafts that are scheduled to be
+// published at or before t.
+func (r *Repo) DueDrafts(ctx context.Context, t time.Time) ([]Draft, error) {
+	res, err := r.drafts.Find(ctx, bson.M{
+		"publishAt":   bson.M{"$lte": t},
+		"publishedAt": bson.M{"$exists": false},
+	}, options.Find().
+		SetSort(bson.D{{Key: "publishAt", Value: 1}}).
+		SetProjection(bson.M{"officeHours": 0}))
+	if err != nil {
+		return nil, err
+	}
+
+	var drafts []Draft
+	if err := res.All(ctx, &drafts); err != nil {
+		return nil, fmt.Errorf("failed to decode drafts: %w", err)
+	}
+
+	return drafts, nil
+}
+
+// draftError returns the reason why the draft id could not be modified.
+func (r *Repo) draftError(ctx context.Context, id primitive.ObjectID) error {
+	var draft Draft
+	if err := r.drafts.FindOne(ctx, bson.M{"_id": id}, options.FindOne().
+		SetProjection(bson.M{"officeHours": 0})).Decode(&draft); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrDraftNotFound
+		}
+
+		return err
+	}
+
+	if draft.PublishedAt != nil {
+		return ErrDraftPublished
+	}
+
+	return ErrRevisionMismatch
+}
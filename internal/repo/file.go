@@ -0,0 +1,156 @@
+package repo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// FileStore is an OfficeHourStore that keeps all data in memory and writes
+// it to a JSON or YAML file (depending on the file extension) after each
+// change. It is meant for small, single-replica installations.
+//
+// The file may be edited by hand while the service is stopped. A schedule
+// document created by ExportSchedule is a valid store file. The full audit
+// trail is kept while schedule versions may be limited using
+// SetVersionRetention.
+type FileStore struct {
+	*MemoryStore
+
+	path string
+
+	// last holds the contents of the file as last read or written. It is
+	// used to roll back the in-memory state if the file cannot be written.
+	last []byte
+}
+
+// NewFileStore returns a new FileStore that stores its data at path. If the
+// file exists, the data is loaded from it.
+func NewFileStore(path string) (*FileStore, error) {
+	fileStore := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		path:        path,
+	}
+
+	blob, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// start with an empty store
+
+	case err != nil:
+		return nil, fmt.Errorf("failed to read store file: %w", err)
+
+	default:
+		fileStore.last = blob
+
+		if err := fileStore.decode(blob, &fileStore.state); err != nil {
+			return nil, fmt.Errorf("failed to decode store file %q: %w", path, err)
+		}
+	}
+
+	fileStore.persist = fileStore.write
+
+	return fileStore, nil
+}
+
+// write writes state to the store file. The file is replaced atomically so
+// a crash while writing does not corrupt it. If the file cannot be written,
+// state is reset to the last written contents so the in-memory state does
+// not diverge from the file.
+func (f *FileStore) write(state *storeState) error {
+	if err := f.writeFile(state); err != nil {
+		var previous storeState
+		if f.last != nil {
+			if derr := f.decode(f.last, &previous); derr != nil {
+				return errors.Join(err, fmt.Errorf("failed to roll back: %w", derr))
+			}
+		}
+
+		*state = previous
+
+		return err
+	}
+
+	return nil
+}
+
+func (f *FileStore) writeFile(state *storeState) error {
+	var (
+		blob []byte
+		err  error
+	)
+
+	if storeFormat(f.path) == FormatJSON {
+		blob, err = json.MarshalIndent(state, "", "  ")
+	} else {
+		blob, err = yaml.Marshal(state)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode store file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), "."+filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(blob); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write store file: %w", err)
+	}
+
+	f.last = blob
+
+	return nil
+}
+
+// decode decodes the contents of the store file into state.
+func (f *FileStore) decode(blob []byte, state *storeState) error {
+	var err error
+	if storeFormat(f.path) == FormatJSON {
+		err = json.Unmarshal(blob, state)
+	} else {
+		err = yaml.Unmarshal(blob, state)
+	}
+	if err != nil {
+		return err
+	}
+
+	// assign a name to office hours that have been added by hand
+	for idx := range state.OfficeHours {
+		if state.OfficeHours[idx].ID.IsZero() {
+			id, err := derivedID(state.OfficeHours[idx])
+			if err != nil {
+				return err
+			}
+
+			state.OfficeHours[idx].ID = id
+		}
+	}
+
+	return nil
+}
+
+// storeFormat returns the document format for the file at path.
+func storeFormat(path string) string {
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return FormatJSON
+	}
+
+	return FormatYAML
+}
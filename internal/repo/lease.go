@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AcquireLease tries to acquire or renew the lease name for holder. It
+// returns true if holder owns the lease for ttl afterwards.
+func (r *Repo) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expiresAt": bson.M{"$lt": now}},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"holder":    holder,
+			"expiresAt": now.Add(ttl),
+		},
+	}
+
+	_, err := r.leases.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		// The lease exists but is held by someone else so the upsert
+		// tried to insert a second document with the same ID.
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to acquire lease %q: %w", name, err)
+	}
+
+	return true, nil
+}
+
+// ReleaseLease releases the lease name if it is held by holder.
+func (r *Repo) ReleaseLease(ctx context.Context, name string, holder string) error {
+	_, err := r.leases.DeleteOne(ctx, bson.M{
+		"_id":    name,
+		"holder": holder,
+	})
+
+	return err
+}
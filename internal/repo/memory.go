@@ -0,0 +1,907 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/protobuf/proto"
+)
+
+// storeState holds all data of a MemoryStore. It is also the format of the
+// file used by FileStore. Since office hours use the same key as in a
+// ScheduleDocument, an exported schedule can be used as a store file.
+type storeState struct {
+	OfficeHours []OfficeHourModel `json:"officeHours"`
+	Trash       []OfficeHourModel `json:"trash,omitempty"`
+	Overrides   []Override        `json:"overrides,omitempty"`
+	Drafts      []Draft           `json:"drafts,omitempty"`
+	Versions    []ScheduleVersion `json:"versions,omitempty"`
+	Audit       []AuditEntry      `json:"audit,omitempty"`
+	Outbox      []OutboxEntry     `json:"outbox,omitempty"`
+}
+
+type memoryLease struct {
+	holder  string
+	expires time.Time
+}
+
+// MemoryStore is an OfficeHourStore that keeps all data in memory. It is
+// meant for tests and single-replica installations that do not need to
+// persist any data.
+type MemoryStore struct {
+	lock   sync.Mutex
+	state  storeState
+	leases map[string]memoryLease
+
+	// persist is called with the lock held after each mutation.
+	persist func(state *storeState) error
+
+	// versionRetention is the number of schedule versions kept. Zero keeps
+	// all versions.
+	versionRetention int
+}
+
+// NewMemoryStore returns a new, empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		leases: make(map[string]memoryLease),
+	}
+}
+
+// SetVersionRetention configures how many schedule versions are kept. Older
+// versions are removed with the next change. Zero, the default, keeps all
+// versions.
+func (s *MemoryStore) SetVersionRetention(n int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.versionRetention = n
+}
+
+func (s *MemoryStore) UpsertOfficeHours(ctx context.Context, model *OfficeHourModel) (*OfficeHourModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if model.ID.IsZero() {
+		model.ID = primitive.NewObjectIDFromTimestamp(time.Now())
+	}
+
+	// upserting an office hour that is in the trash restores it
+	model.DeletedAt = nil
+	model.DeletedBy = nil
+
+	var previous *OfficeHourModel
+
+	idx := s.indexOf(model.ID)
+	trashIdx := -1
+	if idx >= 0 {
+		p := s.state.OfficeHours[idx]
+		previous = &p
+	} else if trashIdx = s.trashIndex(model.ID); trashIdx >= 0 {
+		p := s.state.Trash[trashIdx]
+		previous = &p
+	}
+
+	var revision int64
+	if previous != nil {
+		revision = previous.Revision
+	}
+
+	// check the revision before modifying any state so a rejected upsert
+	// does not lose a trashed office hour.
+	switch {
+	case model.Revision == AnyRevision:
+		if previous == nil {
+			return nil, ErrNotFound
+		}
+
+	case model.Revision != 0 && model.Revision != revision:
+		return nil, ErrRevisionMismatch
+	}
+
+	if trashIdx >= 0 {
+		s.state.Trash = slices.Delete(s.state.Trash, trashIdx, trashIdx+1)
+	}
+
+	model.Revision = revision + 1
+
+	current := cloneModel(*model)
+
+	if idx >= 0 {
+		s.state.OfficeHours[idx] = current
+	} else {
+		s.state.OfficeHours = append(s.state.OfficeHours, current)
+	}
+
+	s.audit(ctx, AuditActionUpsert, model.ID.Hex(), previous, &current)
+	s.snapshot(ctx, "upsert "+model.ID.Hex())
+
+	if err := s.commit(); err != nil {
+		return nil, err
+	}
+
+	result := cloneModel(current)
+
+	return &result, nil
+}
+
+func (s *MemoryStore) ListOfficeHours(_ context.Context, location string) ([]OfficeHourModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var result []OfficeHourModel
+	for _, m := range s.state.OfficeHours {
+		if location == "" || m.Location == location {
+			result = append(result, cloneModel(m))
+		}
+	}
+
+	return result, nil
+}
+
+func (s *MemoryStore) GetOfficeHour(_ context.Context, name string) (*OfficeHourModel, error) {
+	oid, err := primitive.ObjectIDFromHex(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid office-hour name: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx := s.indexOf(oid)
+	if idx < 0 {
+		return nil, ErrNotFound
+	}
+
+	result := cloneModel(s.state.OfficeHours[idx])
+
+	return &result, nil
+}
+
+func (s *MemoryStore) ListLocations(_ context.Context) ([]string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	locations := []string{""}
+	for _, m := range s.state.OfficeHours {
+		if m.Location != "" && !slices.Contains(locations, m.Location) {
+			locations = append(locations, m.Location)
+		}
+	}
+
+	return locations, nil
+}
+
+func (s *MemoryStore) DeleteOfficeHour(ctx context.Context, name string) error {
+	oid, err := primitive.ObjectIDFromHex(name)
+	if err != nil {
+		return fmt.Errorf("invalid office-hour name: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx := s.indexOf(oid)
+	if idx < 0 {
+		return ErrNotFound
+	}
+
+	previous := s.state.OfficeHours[idx]
+	s.trash(ctx, idx)
+
+	s.audit(ctx, AuditActionDelete, name, &previous, nil)
+	s.snapshot(ctx, "delete "+name)
+
+	return s.commit()
+}
+
+func (s *MemoryStore) ListDeletedOfficeHours(_ context.Context, location string) ([]OfficeHourModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var result []OfficeHourModel
+	for _, m := range s.state.Trash {
+		if location == "" || m.Location == location {
+			result = append(result, cloneModel(m))
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b OfficeHourModel) int {
+		return deletedAt(b).Compare(deletedAt(a))
+	})
+
+	return result, nil
+}
+
+func (s *MemoryStore) RestoreOfficeHour(ctx context.Context, name string) (*OfficeHourModel, error) {
+	oid, err := primitive.ObjectIDFromHex(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid office-hour name: %w", err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx := s.trashIndex(oid)
+	if idx < 0 {
+		return nil, ErrNotFound
+	}
+
+	previous := s.state.Trash[idx]
+	current := previous.restored()
+
+	live := slices.DeleteFunc(cloneModels(s.state.OfficeHours), func(m OfficeHourModel) bool {
+		return m.Location != current.Location
+	})
+
+	if err := current.ValidateWith(live); err != nil {
+		return nil, err
+	}
+
+	s.state.Trash = slices.Delete(s.state.Trash, idx, idx+1)
+	s.state.OfficeHours = append(s.state.OfficeHours, cloneModel(current))
+
+	s.audit(ctx, AuditActionRestore, name, &previous, &current)
+	s.snapshot(ctx, "restore "+name)
+
+	if err := s.commit(); err != nil {
+		return nil, err
+	}
+
+	return &current, nil
+}
+
+func (s *MemoryStore) PurgeDeletedOfficeHours(_ context.Context, t time.Time) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	count := len(s.state.Trash)
+	s.state.Trash = slices.DeleteFunc(s.state.Trash, func(m OfficeHourModel) bool {
+		return deletedAt(m).Before(t)
+	})
+
+	count -= len(s.state.Trash)
+	if count == 0 {
+		return 0, nil
+	}
+
+	return count, s.commit()
+}
+
+func (s *MemoryStore) FindByTime(ctx context.Context, t time.Time, location string) ([]OfficeHourModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hours, err := Schedule(s.state.OfficeHours).FindByTime(ctx, t, location)
+
+	return cloneModels(hours), err
+}
+
+func (s *MemoryStore) FindBetween(ctx context.Context, from, to time.Time, location string) ([]OfficeHourModel, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	hours, err := Schedule(s.state.OfficeHours).FindBetween(ctx, from, to, location)
+
+	return cloneModels(hours), err
+}
+
+func (s *MemoryStore) ListAuditEntries(_ context.Context, name string) ([]AuditEntry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var entries []AuditEntry
+	for idx := len(s.state.Audit) - 1; idx >= 0; idx-- {
+		if s.state.Audit[idx].OfficeHour == name {
+			entries = append(entries, s.state.Audit[idx])
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *MemoryStore) ListVersions(_ context.Context) ([]ScheduleVersion, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	versions := make([]ScheduleVersion, 0, len(s.state.Versions))
+	for idx := len(s.state.Versions) - 1; idx >= 0; idx-- {
+		v := s.state.Versions[idx]
+		v.OfficeHours = nil
+
+		versions = append(versions, v)
+	}
+
+	return versions, nil
+}
+
+func (s *MemoryStore) GetVersion(_ context.Context, version int64) (*ScheduleVersion, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	v, err := s.version(version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (s *MemoryStore) RollbackToVersion(ctx context.Context, version int64) (*ScheduleVersion, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	target, err := s.version(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Schedule(target.OfficeHours).Validate(); err != nil {
+		return nil, err
+	}
+
+	s.applyDiff(ctx, DiffSchedules(s.state.OfficeHours, target.OfficeHours))
+	v := s.snapshot(ctx, fmt.Sprintf("rollback to version %d", version))
+
+	if err := s.commit(); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (s *MemoryStore) SetOverride(_ context.Context, o *Override) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.state.Overrides = slices.DeleteFunc(s.state.Overrides, func(other Override) bool {
+		return other.Location == o.Location
+	})
+	s.state.Overrides = append(s.state.Overrides, *o)
+
+	return s.commit()
+}
+
+func (s *MemoryStore) GetOverride(_ context.Context, location string) (*Override, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, o := range s.state.Overrides {
+		if o.Location == location {
+			return &o, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (s *MemoryStore) RevokeOverride(_ context.Context, location string, t time.Time) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for idx, o := range s.state.Overrides {
+		if o.Location == location && (o.Until == nil || o.Until.After(t)) {
+			s.state.Overrides[idx].Until = &t
+
+			return s.commit()
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (s *MemoryStore) CreateDraft(ctx context.Context, description string) (*Draft, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+
+	draft := Draft{
+		ID:            primitive.NewObjectID(),
+		Description:   description,
+		OfficeHours:   cloneModels(s.state.OfficeHours),
+		BaseRevisions: baseRevisions(s.state.OfficeHours),
+		Revision:      1,
+		Actor:         ActorFrom(ctx),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	s.state.Drafts = append(s.state.Drafts, draft)
+
+	if err := s.commit(); err != nil {
+		return nil, err
+	}
+
+	result := cloneDraft(draft)
+
+	return &result, nil
+}
+
+func (s *MemoryStore) GetDraft(_ context.Context, id string) (*Draft, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx, err := s.draftIndex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := cloneDraft(s.state.Drafts[idx])
+
+	return &result, nil
+}
+
+func (s *MemoryStore) ListDrafts(_ context.Context) ([]Draft, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	drafts := make([]Draft, 0, len(s.state.Drafts))
+	for _, d := range s.state.Drafts {
+		d.OfficeHours = nil
+		drafts = append(drafts, d)
+	}
+
+	slices.SortStableFunc(drafts, func(a, b Draft) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+
+	return drafts, nil
+}
+
+func (s *MemoryStore) UpdateDraft(_ context.Context, draft *Draft) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx, err := s.draftIndex(draft.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	stored := &s.state.Drafts[idx]
+	if stored.PublishedAt != nil {
+		return ErrDraftPublished
+	}
+
+	if stored.Revision != draft.Revision {
+		return ErrRevisionMismatch
+	}
+
+	draft.UpdatedAt = time.Now()
+	draft.Revision++
+
+	stored.OfficeHours = cloneModels(draft.OfficeHours)
+	stored.PublishAt = draft.PublishAt
+	stored.UpdatedAt = draft.UpdatedAt
+	stored.Revision = draft.Revision
+
+	return s.commit()
+}
+
+func (s *MemoryStore) DeleteDraft(_ context.Context, id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx, err := s.draftIndex(id)
+	if err != nil {
+		return err
+	}
+
+	s.state.Drafts = slices.Delete(s.state.Drafts, idx, idx+1)
+
+	return s.commit()
+}
+
+func (s *MemoryStore) PublishDraft(ctx context.Context, id string) (*ScheduleVersion, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	idx, err := s.draftIndex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := &s.state.Drafts[idx]
+	if draft.PublishedAt != nil {
+		return nil, ErrDraftPublished
+	}
+
+	if err := draft.checkBase(s.state.OfficeHours); err != nil {
+		return nil, err
+	}
+
+	if err := draft.Schedule().Validate(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	draft.PublishedAt = &now
+
+	s.applyDiff(ctx, DiffSchedules(s.state.OfficeHours, draft.OfficeHours))
+	v := s.snapshot(ctx, "publish draft "+id)
+
+	if err := s.commit(); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+func (s *MemoryStore) DueDrafts(_ context.Context, t time.Time) ([]Draft, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var drafts []Draft
+	for _, d := range s.state.Drafts {
+		if d.PublishedAt == nil && d.PublishAt != nil && !d.PublishAt.After(t) {
+			d.OfficeHours = nil
+			drafts = append(drafts, d)
+		}
+	}
+
+	slices.SortStableFunc(drafts, func(a, b Draft) int {
+		return a.PublishAt.Compare(*b.PublishAt)
+	})
+
+	return drafts, nil
+}
+
+func (s *MemoryStore) ExportSchedule(_ context.Context) (*ScheduleDocument, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return &ScheduleDocument{
+		OfficeHours: cloneModels(Schedule(s.state.OfficeHours).Sorted()),
+	}, nil
+}
+
+func (s *MemoryStore) ImportSchedule(ctx context.Context, doc *ScheduleDocument, dryRun bool) (ScheduleDiff, *ScheduleVersion, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	diff := DiffSchedules(s.state.OfficeHours, doc.OfficeHours)
+	if dryRun {
+		return diff, nil, nil
+	}
+
+	s.applyDiff(ctx, diff)
+	v := s.snapshot(ctx, "import")
+
+	if err := s.commit(); err != nil {
+		return diff, nil, err
+	}
+
+	return diff, &v, nil
+}
+
+func (s *MemoryStore) EnqueueEvent(_ context.Context, key string, msg proto.Message) error {
+	entry, err := newOutboxEntry(key, msg)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.outboxIndex(key) >= 0 {
+		return nil
+	}
+
+	s.state.Outbox = append(s.state.Outbox, entry)
+
+	return s.commit()
+}
+
+func (s *MemoryStore) PendingEvents(_ context.Context) ([]OutboxEntry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var entries []OutboxEntry
+	for _, e := range s.state.Outbox {
+		if e.DeliveredAt.IsZero() && e.DeadAt.IsZero() {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *MemoryStore) MarkEventDelivered(_ context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if idx := s.outboxIndex(key); idx >= 0 {
+		s.state.Outbox[idx].DeliveredAt = time.Now()
+	}
+
+	return s.commit()
+}
+
+func (s *MemoryStore) MarkEventFailed(_ context.Context, key string, nextAttempt time.Time, deliveryErr error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if idx := s.outboxIndex(key); idx >= 0 {
+		s.state.Outbox[idx].NextAttempt = nextAttempt
+		s.state.Outbox[idx].LastError = deliveryErr.Error()
+		s.state.Outbox[idx].Attempts++
+	}
+
+	return s.commit()
+}
+
+func (s *MemoryStore) MarkEventDead(_ context.Context, key string, deliveryErr error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if idx := s.outboxIndex(key); idx >= 0 {
+		s.state.Outbox[idx].DeadAt = time.Now()
+		s.state.Outbox[idx].LastError = deliveryErr.Error()
+		s.state.Outbox[idx].Attempts++
+	}
+
+	return s.commit()
+}
+
+func (s *MemoryStore) AcquireLease(_ context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+
+	if l, ok := s.leases[name]; ok && l.holder != holder && l.expires.After(now) {
+		return false, nil
+	}
+
+	s.leases[name] = memoryLease{
+		holder:  holder,
+		expires: now.Add(ttl),
+	}
+
+	return true, nil
+}
+
+func (s *MemoryStore) ReleaseLease(_ context.Context, name string, holder string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if l, ok := s.leases[name]; ok && l.holder == holder {
+		delete(s.leases, name)
+	}
+
+	return nil
+}
+
+// indexOf returns the index of the office hour id or -1.
+func (s *MemoryStore) indexOf(id primitive.ObjectID) int {
+	return slices.IndexFunc(s.state.OfficeHours, func(m OfficeHourModel) bool {
+		return m.ID == id
+	})
+}
+
+func (s *MemoryStore) trashIndex(id primitive.ObjectID) int {
+	return slices.IndexFunc(s.state.Trash, func(m OfficeHourModel) bool {
+		return m.ID == id
+	})
+}
+
+func (s *MemoryStore) draftIndex(id string) (int, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return -1, fmt.Errorf("invalid draft id: %w", err)
+	}
+
+	idx := slices.IndexFunc(s.state.Drafts, func(d Draft) bool {
+		return d.ID == oid
+	})
+	if idx < 0 {
+		return -1, ErrDraftNotFound
+	}
+
+	return idx, nil
+}
+
+func (s *MemoryStore) outboxIndex(key string) int {
+	return slices.IndexFunc(s.state.Outbox, func(e OutboxEntry) bool {
+		return e.Key == key
+	})
+}
+
+func (s *MemoryStore) version(version int64) (ScheduleVersion, error) {
+	for _, v := range s.state.Versions {
+		if v.Version == version {
+			v.OfficeHours = cloneModels(v.OfficeHours)
+			return v, nil
+		}
+	}
+
+	return ScheduleVersion{}, ErrVersionNotFound
+}
+
+// applyDiff applies diff to the live schedule and records all changes in the
+// audit log.
+func (s *MemoryStore) applyDiff(ctx context.Context, diff ScheduleDiff) {
+	// office hours restored from the trash continue with their revision
+	stored := make(map[primitive.ObjectID]int64)
+	for _, m := range diff.Added {
+		if idx := s.trashIndex(m.ID); idx >= 0 {
+			stored[m.ID] = s.state.Trash[idx].Revision
+		}
+	}
+
+	diff = diff.withRevisions(stored)
+
+	// removed office hours are moved to the trash
+	for _, m := range diff.Removed {
+		if idx := s.indexOf(m.ID); idx >= 0 {
+			s.trash(ctx, idx)
+		}
+
+		s.audit(ctx, AuditActionDelete, m.ID.Hex(), &m, nil)
+	}
+
+	for _, m := range diff.Added {
+		if idx := s.trashIndex(m.ID); idx >= 0 {
+			s.state.Trash = slices.Delete(s.state.Trash, idx, idx+1)
+		}
+
+		s.state.OfficeHours = append(s.state.OfficeHours, cloneModel(m))
+
+		s.audit(ctx, AuditActionUpsert, m.ID.Hex(), nil, &m)
+	}
+
+	for _, c := range diff.Changed {
+		if idx := s.indexOf(c.Current.ID); idx >= 0 {
+			s.state.OfficeHours[idx] = cloneModel(c.Current)
+		}
+
+		s.audit(ctx, AuditActionUpsert, c.Current.ID.Hex(), &c.Previous, &c.Current)
+	}
+}
+
+// trash moves the office hour at idx to the trash.
+func (s *MemoryStore) trash(ctx context.Context, idx int) {
+	now := time.Now()
+	actor := ActorFrom(ctx)
+
+	m := s.state.OfficeHours[idx]
+	m.DeletedAt = &now
+	m.DeletedBy = &actor
+	m.Revision++
+
+	s.state.OfficeHours = slices.Delete(s.state.OfficeHours, idx, idx+1)
+	s.state.Trash = append(s.state.Trash, m)
+}
+
+func (s *MemoryStore) audit(ctx context.Context, action string, name string, previous, current *OfficeHourModel) {
+	entry := AuditEntry{
+		ID:         primitive.NewObjectID(),
+		OfficeHour: name,
+		Action:     action,
+		Actor:      ActorFrom(ctx),
+		Timestamp:  time.Now(),
+	}
+
+	if previous != nil {
+		p := cloneModel(*previous)
+		entry.Previous = &p
+	}
+
+	if current != nil {
+		c := cloneModel(*current)
+		entry.Current = &c
+	}
+
+	s.state.Audit = append(s.state.Audit, entry)
+}
+
+// snapshot stores the live schedule as a new schedule version.
+func (s *MemoryStore) snapshot(ctx context.Context, description string) ScheduleVersion {
+	var last int64
+	if len(s.state.Versions) > 0 {
+		last = s.state.Versions[len(s.state.Versions)-1].Version
+	}
+
+	v := ScheduleVersion{
+		Version:     last + 1,
+		Description: description,
+		Actor:       ActorFrom(ctx),
+		CreatedAt:   time.Now(),
+		OfficeHours: cloneModels(Schedule(s.state.OfficeHours).Sorted()),
+	}
+
+	s.state.Versions = append(s.state.Versions, v)
+
+	return v
+}
+
+// commit removes expired data and persists the state. It must be called
+// with the lock held after each mutation.
+func (s *MemoryStore) commit() error {
+	now := time.Now()
+
+	s.state.Overrides = slices.DeleteFunc(s.state.Overrides, func(o Override) bool {
+		return o.Until != nil && now.Sub(*o.Until) > overrideRetention
+	})
+
+	// keep delivered events for some time so duplicate events are still
+	// detected and dead events so they can be inspected.
+	s.state.Outbox = slices.DeleteFunc(s.state.Outbox, func(e OutboxEntry) bool {
+		return (!e.DeliveredAt.IsZero() && now.Sub(e.DeliveredAt) > 7*24*time.Hour) ||
+			(!e.DeadAt.IsZero() && now.Sub(e.DeadAt) > 30*24*time.Hour)
+	})
+
+	// only keep the most recent versions if configured so the state, and
+	// the file of a FileStore, does not grow without bounds. The audit
+	// trail is always kept in full.
+	if s.versionRetention > 0 {
+		if n := len(s.state.Versions) - s.versionRetention; n > 0 {
+			s.state.Versions = slices.Delete(s.state.Versions, 0, n)
+		}
+	}
+
+	if s.persist == nil {
+		return nil
+	}
+
+	return s.persist(&s.state)
+}
+
+// deletedAt returns the time at which m has been moved to the trash or the
+// zero time if it is unknown.
+func deletedAt(m OfficeHourModel) time.Time {
+	if m.DeletedAt == nil {
+		return time.Time{}
+	}
+
+	return *m.DeletedAt
+}
+
+// cloneModel returns a deep copy of m so callers cannot modify the state of
+// the store through the returned model.
+func cloneModel(m OfficeHourModel) OfficeHourModel {
+	m.TimeRanges = slices.Clone(m.TimeRanges)
+
+	if m.DateRange != nil {
+		dr := *m.DateRange
+		dr.Weekdays = slices.Clone(dr.Weekdays)
+		m.DateRange = &dr
+	}
+
+	if m.DeletedAt != nil {
+		deletedAt := *m.DeletedAt
+		m.DeletedAt = &deletedAt
+	}
+
+	if m.DeletedBy != nil {
+		deletedBy := *m.DeletedBy
+		m.DeletedBy = &deletedBy
+	}
+
+	return m
+}
+
+func cloneModels(models []OfficeHourModel) []OfficeHourModel {
+	if models == nil {
+		return nil
+	}
+
+	result := make([]OfficeHourModel, len(models))
+	for idx, m := range models {
+		result[idx] = cloneModel(m)
+	}
+
+	return result
+}
+
+func cloneDraft(d Draft) Draft {
+	d.OfficeHours = cloneModels(d.OfficeHours)
+	d.BaseRevisions = maps.Clone(d.BaseRevisions)
+
+	return d
+}
@@ -0,0 +1,742 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	monday, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if monday.ID.IsZero() {
+		t.Fatalf("expected a name to be assigned")
+	}
+
+	hours, err := store.FindByTime(ctx, time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC), "")
+	if err != nil || len(hours) != 1 {
+		t.Fatalf("expected to find the monday office hour, got %d (%v)", len(hours), err)
+	}
+
+	if err := store.DeleteOfficeHour(ctx, monday.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	if _, err := store.GetOfficeHour(ctx, monday.ID.Hex()); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+
+	versions, err := store.ListVersions(ctx)
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("expected two schedule versions, got %d (%v)", len(versions), err)
+	}
+
+	if _, err := store.RollbackToVersion(ctx, versions[1].Version); err != nil {
+		t.Fatalf("failed to roll back: %s", err)
+	}
+
+	if _, err := store.GetOfficeHour(ctx, monday.ID.Hex()); err != nil {
+		t.Fatalf("expected office hour to be restored: %s", err)
+	}
+
+	audit, err := store.ListAuditEntries(ctx, monday.ID.Hex())
+	if err != nil || len(audit) != 3 {
+		t.Fatalf("expected three audit entries, got %d (%v)", len(audit), err)
+	}
+}
+
+func TestMemoryStoreSunday(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	// Sunday uses the commonv1.DayOfWeek numbering
+	if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DayOfWeek: 7,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	hours, err := store.FindByTime(ctx, time.Date(2024, time.June, 2, 10, 0, 0, 0, time.UTC), "")
+	if err != nil || len(hours) != 1 {
+		t.Fatalf("expected to find the sunday office hour, got %d (%v)", len(hours), err)
+	}
+}
+
+func TestMemoryStoreRevisions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	created, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DayOfWeek: time.Tuesday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	})
+	if err != nil || created.Revision != 1 {
+		t.Fatalf("expected revision 1, got %d (%v)", created.Revision, err)
+	}
+
+	first, second := *created, *created
+
+	updated, err := store.UpsertOfficeHours(ctx, &first)
+	if err != nil || updated.Revision != 2 {
+		t.Fatalf("expected revision 2, got %d (%v)", updated.Revision, err)
+	}
+
+	if _, err := store.UpsertOfficeHours(ctx, &second); err != ErrRevisionMismatch {
+		t.Fatalf("expected ErrRevisionMismatch but got %v", err)
+	}
+
+	// upserts without a revision always succeed
+	second.Revision = 0
+	if updated, err := store.UpsertOfficeHours(ctx, &second); err != nil || updated.Revision != 3 {
+		t.Fatalf("expected revision 3, got %d (%v)", updated.Revision, err)
+	}
+
+	// AnyRevision matches any revision of an existing office hour
+	second.Revision = AnyRevision
+	if updated, err := store.UpsertOfficeHours(ctx, &second); err != nil || updated.Revision != 4 {
+		t.Fatalf("expected revision 4, got %d (%v)", updated.Revision, err)
+	}
+
+	missing := OfficeHourModel{
+		DayOfWeek: time.Wednesday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+		Revision: AnyRevision,
+	}
+
+	if _, err := store.UpsertOfficeHours(ctx, &missing); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+
+	if hours, _ := store.ListOfficeHours(ctx, ""); len(hours) != 1 {
+		t.Errorf("expected the missing office hour not to be created, got %d office hours", len(hours))
+	}
+}
+
+func TestMemoryStoreTrash(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	created, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		Date: "12-24",
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if err := store.DeleteOfficeHour(ctx, created.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	if hours, _ := store.ListOfficeHours(ctx, ""); len(hours) != 0 {
+		t.Fatalf("expected deleted office hour to be hidden, got %d office hours", len(hours))
+	}
+
+	deleted, err := store.ListDeletedOfficeHours(ctx, "")
+	if err != nil || len(deleted) != 1 || deleted[0].DeletedAt == nil {
+		t.Fatalf("expected one office hour in the trash, got %d (%v)", len(deleted), err)
+	}
+
+	restored, err := store.RestoreOfficeHour(ctx, created.ID.Hex())
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("failed to restore office hour: %v", err)
+	}
+
+	if _, err := store.GetOfficeHour(ctx, created.ID.Hex()); err != nil {
+		t.Fatalf("expected restored office hour: %s", err)
+	}
+
+	if err := store.DeleteOfficeHour(ctx, created.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	if count, err := store.PurgeDeletedOfficeHours(ctx, time.Now().Add(time.Minute)); err != nil || count != 1 {
+		t.Fatalf("expected one office hour to be purged, got %d (%v)", count, err)
+	}
+
+	if _, err := store.RestoreOfficeHour(ctx, created.ID.Hex()); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+}
+
+func TestMemoryStoreStaleUpsertKeepsTrash(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	created, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		Date: "12-24",
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if err := store.DeleteOfficeHour(ctx, created.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	// deleting bumped the revision so the revision of created is stale
+	if _, err := store.UpsertOfficeHours(ctx, created); err != ErrRevisionMismatch {
+		t.Fatalf("expected ErrRevisionMismatch but got %v", err)
+	}
+
+	deleted, err := store.ListDeletedOfficeHours(ctx, "")
+	if err != nil || len(deleted) != 1 || deleted[0].ID != created.ID {
+		t.Fatalf("expected the office hour to stay in the trash, got %d (%v)", len(deleted), err)
+	}
+
+	if hours, _ := store.ListOfficeHours(ctx, ""); len(hours) != 0 {
+		t.Fatalf("expected no live office hours, got %d", len(hours))
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+
+	for _, name := range []string{"store.yaml", "store.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+
+			store, err := NewFileStore(path)
+			if err != nil {
+				t.Fatalf("failed to create file store: %s", err)
+			}
+
+			created, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+				Location: "branch",
+				Date:     "12-24",
+			})
+			if err != nil {
+				t.Fatalf("failed to upsert office hour: %s", err)
+			}
+
+			reopened, err := NewFileStore(path)
+			if err != nil {
+				t.Fatalf("failed to reopen file store: %s", err)
+			}
+
+			m, err := reopened.GetOfficeHour(ctx, created.ID.Hex())
+			if err != nil {
+				t.Fatalf("expected office hour to be persisted: %s", err)
+			}
+
+			if m.Location != "branch" || m.Date != "12-24" {
+				t.Errorf("unexpected office hour after reopening: %+v", m)
+			}
+		})
+	}
+}
+
+func TestFileStoreRollsBackOnWriteFailure(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "data")
+
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+
+	store, err := NewFileStore(filepath.Join(dir, "store.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create file store: %s", err)
+	}
+
+	if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{Date: "12-24"}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	// the store file can no longer be written
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("failed to remove directory: %s", err)
+	}
+
+	if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{Date: "12-31"}); err == nil {
+		t.Fatalf("expected upsert to fail")
+	}
+
+	hours, err := store.ListOfficeHours(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to list office hours: %s", err)
+	}
+
+	if len(hours) != 1 || hours[0].Date != "12-24" {
+		t.Errorf("expected the failed upsert to be rolled back, got %+v", hours)
+	}
+
+	versions, err := store.ListVersions(ctx)
+	if err != nil {
+		t.Fatalf("failed to list versions: %s", err)
+	}
+
+	if len(versions) != 1 {
+		t.Errorf("expected one schedule version but got %d", len(versions))
+	}
+}
+
+func TestMemoryStoreCapsHistory(t *testing.T) {
+	ctx := context.Background()
+
+	upsert := func(store *MemoryStore, count int) {
+		for idx := 0; idx < count; idx++ {
+			if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+				Date:       "12-24",
+				TimeRanges: []DayTimeRange{{Start: DayTime{Hours: 8}, End: DayTime{Hours: 9, Minutes: idx % 60}}},
+			}); err != nil {
+				t.Fatalf("failed to upsert office hour: %s", err)
+			}
+		}
+	}
+
+	// all versions are kept by default
+	store := NewMemoryStore()
+	upsert(store, 110)
+
+	if len(store.state.Versions) != 110 {
+		t.Errorf("expected all 110 schedule versions to be kept but got %d", len(store.state.Versions))
+	}
+
+	store = NewMemoryStore()
+	store.SetVersionRetention(100)
+	upsert(store, 110)
+
+	if len(store.state.Versions) != 100 {
+		t.Errorf("expected 100 schedule versions but got %d", len(store.state.Versions))
+	}
+
+	if last := store.state.Versions[len(store.state.Versions)-1].Version; last != 110 {
+		t.Errorf("expected the latest version to be 110 but got %d", last)
+	}
+}
+
+func TestMemoryStoreDrafts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	monday, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	draft, err := store.CreateDraft(ctx, "summer")
+	if err != nil {
+		t.Fatalf("failed to create draft: %s", err)
+	}
+
+	if len(draft.OfficeHours) != 1 || draft.OfficeHours[0].ID != monday.ID {
+		t.Fatalf("expected the draft to start as a copy of the live schedule, got %+v", draft.OfficeHours)
+	}
+
+	if draft.BaseRevisions[monday.ID.Hex()] != monday.Revision {
+		t.Fatalf("expected the base revision %d, got %v", monday.Revision, draft.BaseRevisions)
+	}
+
+	// a concurrent update of the draft must not be overwritten
+	stale, err := store.GetDraft(ctx, draft.ID.Hex())
+	if err != nil {
+		t.Fatalf("failed to get draft: %s", err)
+	}
+
+	draft.OfficeHours[0].TimeRanges[0].End = DayTime{Hours: 13}
+	if err := store.UpdateDraft(ctx, draft); err != nil {
+		t.Fatalf("failed to update draft: %s", err)
+	}
+
+	stale.OfficeHours = nil
+	if err := store.UpdateDraft(ctx, stale); !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch for a stale draft but got %v", err)
+	}
+
+	// the live schedule is not affected until the draft is published
+	live, err := store.GetOfficeHour(ctx, monday.ID.Hex())
+	if err != nil || live.TimeRanges[0].End.Hours != 12 {
+		t.Fatalf("expected the live office hour to be unchanged, got %+v (%v)", live, err)
+	}
+
+	version, err := store.PublishDraft(ctx, draft.ID.Hex())
+	if err != nil {
+		t.Fatalf("failed to publish draft: %s", err)
+	}
+
+	if version.Description != "publish draft "+draft.ID.Hex() {
+		t.Errorf("unexpected version description %q", version.Description)
+	}
+
+	live, err = store.GetOfficeHour(ctx, monday.ID.Hex())
+	if err != nil || live.TimeRanges[0].End.Hours != 13 {
+		t.Fatalf("expected the draft to be published, got %+v (%v)", live, err)
+	}
+
+	if _, err := store.PublishDraft(ctx, draft.ID.Hex()); !errors.Is(err, ErrDraftPublished) {
+		t.Fatalf("expected ErrDraftPublished but got %v", err)
+	}
+}
+
+func TestMemoryStorePublishOutdatedDraft(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	monday, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	draft, err := store.CreateDraft(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to create draft: %s", err)
+	}
+
+	// the live schedule is changed after the draft has been created
+	monday.TimeRanges[0].Start = DayTime{Hours: 7}
+	if _, err := store.UpsertOfficeHours(ctx, monday); err != nil {
+		t.Fatalf("failed to update office hour: %s", err)
+	}
+
+	if _, err := store.PublishDraft(ctx, draft.ID.Hex()); !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch but got %v", err)
+	}
+
+	live, err := store.GetOfficeHour(ctx, monday.ID.Hex())
+	if err != nil || live.TimeRanges[0].Start.Hours != 7 {
+		t.Fatalf("expected the live change to be kept, got %+v (%v)", live, err)
+	}
+
+	stored, err := store.GetDraft(ctx, draft.ID.Hex())
+	if err != nil || stored.PublishedAt != nil {
+		t.Fatalf("expected the draft to stay unpublished, got %+v (%v)", stored, err)
+	}
+
+	// office hours created after the draft conflict as well
+	draft, err = store.CreateDraft(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to create draft: %s", err)
+	}
+
+	if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DayOfWeek: time.Tuesday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if _, err := store.PublishDraft(ctx, draft.ID.Hex()); !errors.Is(err, ErrRevisionMismatch) {
+		t.Fatalf("expected ErrRevisionMismatch but got %v", err)
+	}
+}
+
+func TestMemoryStoreDueDrafts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	now := time.Now()
+
+	schedule := func(at *time.Time) *Draft {
+		draft, err := store.CreateDraft(ctx, "")
+		if err != nil {
+			t.Fatalf("failed to create draft: %s", err)
+		}
+
+		draft.PublishAt = at
+		if err := store.UpdateDraft(ctx, draft); err != nil {
+			t.Fatalf("failed to schedule draft: %s", err)
+		}
+
+		return draft
+	}
+
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	justNow := now.Add(-time.Minute)
+
+	schedule(nil)
+	schedule(&later)
+	second := schedule(&justNow)
+	first := schedule(&earlier)
+
+	published := schedule(&earlier)
+	if _, err := store.PublishDraft(ctx, published.ID.Hex()); err != nil {
+		t.Fatalf("failed to publish draft: %s", err)
+	}
+
+	due, err := store.DueDrafts(ctx, now)
+	if err != nil {
+		t.Fatalf("failed to load due drafts: %s", err)
+	}
+
+	if len(due) != 2 {
+		t.Fatalf("expected two due drafts, got %d", len(due))
+	}
+
+	if due[0].ID != first.ID || due[1].ID != second.ID {
+		t.Errorf("expected due drafts to be sorted by their publish time")
+	}
+}
+
+func TestMemoryStoreLeases(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	acquire := func(holder string, ttl time.Duration) bool {
+		t.Helper()
+
+		ok, err := store.AcquireLease(ctx, "leader", holder, ttl)
+		if err != nil {
+			t.Fatalf("failed to acquire lease: %s", err)
+		}
+
+		return ok
+	}
+
+	if !acquire("a", 50*time.Millisecond) {
+		t.Fatalf("expected a to acquire the free lease")
+	}
+
+	// the holder may renew its lease, others must wait until it expires
+	if !acquire("a", 50*time.Millisecond) {
+		t.Fatalf("expected a to renew its lease")
+	}
+
+	if acquire("b", time.Hour) {
+		t.Fatalf("expected b to not acquire a lease held by a")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if !acquire("b", time.Hour) {
+		t.Fatalf("expected b to take over the expired lease")
+	}
+
+	if acquire("a", time.Hour) {
+		t.Fatalf("expected a to lose the lease after it expired")
+	}
+
+	// releasing a lease held by someone else is a no-op
+	if err := store.ReleaseLease(ctx, "leader", "a"); err != nil {
+		t.Fatalf("failed to release lease: %s", err)
+	}
+
+	if acquire("a", time.Hour) {
+		t.Fatalf("expected the lease to still be held by b")
+	}
+
+	if err := store.ReleaseLease(ctx, "leader", "b"); err != nil {
+		t.Fatalf("failed to release lease: %s", err)
+	}
+
+	if !acquire("a", time.Hour) {
+		t.Fatalf("expected a to acquire the released lease")
+	}
+}
+
+func TestMemoryStoreKeepsAuditTrail(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	m := &OfficeHourModel{Date: "12-24"}
+	for idx := 0; idx < 1100; idx++ {
+		m.TimeRanges = []DayTimeRange{{Start: DayTime{Hours: 8}, End: DayTime{Hours: 9, Minutes: idx % 60}}}
+
+		var err error
+		if m, err = store.UpsertOfficeHours(ctx, m); err != nil {
+			t.Fatalf("failed to upsert office hour: %s", err)
+		}
+	}
+
+	audit, err := store.ListAuditEntries(ctx, m.ID.Hex())
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %s", err)
+	}
+
+	if len(audit) != 1100 {
+		t.Errorf("expected all 1100 audit entries to be kept but got %d", len(audit))
+	}
+}
+
+func TestMemoryStoreRollbackRejectsConflicts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	// the store does not validate single upserts so a conflicting schedule
+	// version can be created, for example by edits made before a validation
+	// rule has been introduced.
+	for _, hours := range []int{8, 14} {
+		if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+			DayOfWeek:  time.Monday,
+			TimeRanges: []DayTimeRange{{Start: DayTime{Hours: hours}, End: DayTime{Hours: hours + 2}}},
+		}); err != nil {
+			t.Fatalf("failed to upsert office hour: %s", err)
+		}
+	}
+
+	conflicting, err := store.ListVersions(ctx)
+	if err != nil {
+		t.Fatalf("failed to list versions: %s", err)
+	}
+
+	hours, _ := store.ListOfficeHours(ctx, "")
+	if err := store.DeleteOfficeHour(ctx, hours[0].ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	var verr *ValidationError
+	if _, err := store.RollbackToVersion(ctx, conflicting[0].Version); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error but got %v", err)
+	}
+
+	if hours, _ := store.ListOfficeHours(ctx, ""); len(hours) != 1 {
+		t.Errorf("expected the rollback to not be applied, got %d office hours", len(hours))
+	}
+}
+
+func TestMemoryStorePublishConflictingDraft(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	draft, err := store.CreateDraft(ctx, "")
+	if err != nil {
+		t.Fatalf("failed to create draft: %s", err)
+	}
+
+	// drafts are validated by the service when edited, the store does not
+	// prevent storing conflicting office hours.
+	for idx, hours := range []int{8, 14} {
+		draft.OfficeHours = append(draft.OfficeHours, OfficeHourModel{
+			ID:         primitive.ObjectID{11: byte(idx + 1)},
+			DayOfWeek:  time.Monday,
+			TimeRanges: []DayTimeRange{{Start: DayTime{Hours: hours}, End: DayTime{Hours: hours + 2}}},
+		})
+	}
+
+	if err := store.UpdateDraft(ctx, draft); err != nil {
+		t.Fatalf("failed to update draft: %s", err)
+	}
+
+	var verr *ValidationError
+	if _, err := store.PublishDraft(ctx, draft.ID.Hex()); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error but got %v", err)
+	}
+
+	if hours, _ := store.ListOfficeHours(ctx, ""); len(hours) != 0 {
+		t.Errorf("expected the draft to not be published, got %d office hours", len(hours))
+	}
+
+	stored, err := store.GetDraft(ctx, draft.ID.Hex())
+	if err != nil || stored.PublishedAt != nil {
+		t.Errorf("expected the draft to stay unpublished, got %+v (%v)", stored, err)
+	}
+}
+
+func TestMemoryStoreRestoreRejectsConflicts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	deleted, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{Date: "12-24"})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if err := store.DeleteOfficeHour(ctx, deleted.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	// a replacement has been created while the office hour was deleted
+	if _, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		Date:       "12-24",
+		TimeRanges: []DayTimeRange{{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}}},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	var verr *ValidationError
+	if _, err := store.RestoreOfficeHour(ctx, deleted.ID.Hex()); !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error but got %v", err)
+	}
+
+	if trash, _ := store.ListDeletedOfficeHours(ctx, ""); len(trash) != 1 {
+		t.Errorf("expected the office hour to stay in the trash, got %d", len(trash))
+	}
+}
+
+func TestMemoryStoreReturnsCopies(t *testing.T) {
+	ctx := WithActor(context.Background(), Actor{ID: "alice"})
+	store := NewMemoryStore()
+
+	created, err := store.UpsertOfficeHours(ctx, &OfficeHourModel{
+		DateRange: &DateRange{From: "07-15", To: "08-15", Yearly: true, Weekdays: []time.Weekday{time.Monday}},
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	// modifying the returned model must not modify the stored one
+	created.DateRange.From = "01-01"
+	created.DateRange.Weekdays[0] = time.Friday
+
+	hour, err := store.GetOfficeHour(ctx, created.ID.Hex())
+	if err != nil {
+		t.Fatalf("failed to get office hour: %s", err)
+	}
+
+	hour.DateRange.To = "12-31"
+	hour.DateRange.Weekdays[0] = time.Sunday
+
+	if err := store.DeleteOfficeHour(ctx, created.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	deleted, err := store.ListDeletedOfficeHours(ctx, "")
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("expected one deleted office hour, got %d (%v)", len(deleted), err)
+	}
+
+	*deleted[0].DeletedAt = time.Time{}
+	deleted[0].DeletedBy.ID = "mallory"
+
+	deleted, err = store.ListDeletedOfficeHours(ctx, "")
+	if err != nil || len(deleted) != 1 {
+		t.Fatalf("expected one deleted office hour, got %d (%v)", len(deleted), err)
+	}
+
+	dr := deleted[0].DateRange
+	if dr.From != "07-15" || dr.To != "08-15" || dr.Weekdays[0] != time.Monday {
+		t.Errorf("expected the date range to be unchanged, got %+v", dr)
+	}
+
+	if deleted[0].DeletedAt.IsZero() || deleted[0].DeletedBy.ID != "alice" {
+		t.Errorf("expected the deletion to be unchanged, got %v by %+v", deleted[0].DeletedAt, deleted[0].DeletedBy)
+	}
+}
@@ -2,6 +2,8 @@ package repo
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	commonv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/common/v1"
@@ -10,27 +12,598 @@ import (
 )
 
 type TimeRange struct {
-	From time.Time `bson:"from,omitempty"`
-	To   time.Time `bson:"to,omitempty"`
+	From time.Time `bson:"from,omitempty" json:"from,omitempty"`
+	To   time.Time `bson:"to,omitempty" json:"to,omitempty"`
 }
 
 type DayTime struct {
-	Hours   int `bson:"hours"`
-	Minutes int `bson:"minutes"`
-	Seconds int `bson:"seconds"`
+	Hours   int `bson:"hours" json:"hours"`
+	Minutes int `bson:"minutes" json:"minutes"`
+	Seconds int `bson:"seconds" json:"seconds"`
+}
+
+func (dt DayTime) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d", dt.Hours, dt.Minutes, dt.Seconds)
+}
+
+// isValid reports whether dt is a valid time of the day. 24:00:00 is
+// allowed to denote the end of the day.
+func (dt DayTime) isValid() bool {
+	if dt.Hours == 24 {
+		return dt.Minutes == 0 && dt.Seconds == 0
+	}
+
+	return dt.Hours >= 0 && dt.Hours < 24 &&
+		dt.Minutes >= 0 && dt.Minutes < 60 &&
+		dt.Seconds >= 0 && dt.Seconds < 60
+}
+
+// offset returns dt as the duration since midnight.
+func (dt DayTime) offset() time.Duration {
+	return time.Duration(dt.Hours)*time.Hour +
+		time.Duration(dt.Minutes)*time.Minute +
+		time.Duration(dt.Seconds)*time.Second
+}
+
+// At returns dt at the day of t.
+func (dt DayTime) At(t time.Time) time.Time {
+	year, month, day := t.Date()
+
+	return time.Date(year, month, day, dt.Hours, dt.Minutes, dt.Seconds, 0, t.Location())
+}
+
+// OpeningMode describes how the clinic can be reached during a time range.
+type OpeningMode string
+
+const (
+	// ModeConsultation is the regular consultation mode. It is used if a
+	// time range does not specify a mode.
+	ModeConsultation OpeningMode = "consultation"
+
+	// ModeAppointmentsOnly means that only patients with an appointment
+	// are treated.
+	ModeAppointmentsOnly OpeningMode = "appointments-only"
+
+	// ModeEmergencyOnly means that only emergencies are treated.
+	ModeEmergencyOnly OpeningMode = "emergency-only"
+
+	// ModePhoneOnly means that the clinic is closed but can be reached by
+	// phone. Time ranges with this mode do not open a location.
+	ModePhoneOnly OpeningMode = "phone-only"
+)
+
+// IsValid reports whether m is a known opening mode. The empty mode is
+// valid and treated as ModeConsultation.
+func (m OpeningMode) IsValid() bool {
+	switch m {
+	case "", ModeConsultation, ModeAppointmentsOnly, ModeEmergencyOnly, ModePhoneOnly:
+		return true
+	}
+
+	return false
 }
 
 type DayTimeRange struct {
-	Start DayTime `bson:"start"`
-	End   DayTime `bson:"end"`
+	Start DayTime     `bson:"start" json:"start"`
+	End   DayTime     `bson:"end" json:"end"`
+	Mode  OpeningMode `bson:"mode,omitempty" json:"mode,omitempty"`
+}
+
+// OpeningMode returns the opening mode of tr.
+func (tr DayTimeRange) OpeningMode() OpeningMode {
+	if tr.Mode == "" {
+		return ModeConsultation
+	}
+
+	return tr.Mode
+}
+
+// At returns the start and end time of tr at the day of t. If the end of tr
+// is not after its start, the range spans midnight and ends on the following
+// day.
+func (tr DayTimeRange) At(t time.Time) (time.Time, time.Time) {
+	start := tr.Start.At(t)
+	end := tr.End.At(t)
+
+	if !end.After(start) {
+		year, month, day := t.Date()
+		end = tr.End.At(time.Date(year, month, day+1, 0, 0, 0, 0, t.Location()))
+	}
+
+	return start, end
 }
 
+// overlaps reports whether tr and other overlap if both apply at the same
+// day. Ranges are compared modulo 24 hours so a range that spans midnight
+// also overlaps with ranges at the start of the day (for example 22:00-02:00
+// and 01:00-03:00).
+func (tr DayTimeRange) overlaps(other DayTimeRange) bool {
+	start, end := tr.bounds()
+	otherStart, otherEnd := other.bounds()
+
+	for _, shift := range []time.Duration{-24 * time.Hour, 0, 24 * time.Hour} {
+		if start < otherEnd+shift && otherStart+shift < end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bounds returns the start and end of tr as offsets from the start of the
+// day. Ranges that span midnight end after 24 hours.
+func (tr DayTimeRange) bounds() (time.Duration, time.Duration) {
+	start, end := tr.Start.offset(), tr.End.offset()
+	if end <= start {
+		end += 24 * time.Hour
+	}
+
+	return start, end
+}
+
+// DateRange is a period of days. From and To are both inclusive and
+// formatted as YYYY-MM-DD or, for yearly recurring ranges, as MM-DD. Yearly
+// ranges may span the turn of the year (for example from 12-24 to 01-06).
+type DateRange struct {
+	From   string `bson:"from" json:"from"`
+	To     string `bson:"to" json:"to"`
+	Yearly bool   `bson:"yearly,omitempty" json:"yearly,omitempty"`
+
+	// Weekdays may be set to only apply the range to some days of the week,
+	// for example to keep the location closed on weekends during a
+	// reduced-hours period. It uses the same numbering as
+	// OfficeHourModel.DayOfWeek (Sunday is 7). If empty, the range applies
+	// to every day.
+	Weekdays []time.Weekday `bson:"weekdays,omitempty" json:"weekdays,omitempty"`
+}
+
+// Contains reports whether the day of t is part of r.
+func (r DateRange) Contains(t time.Time) bool {
+	if len(r.Weekdays) > 0 && !slices.Contains(r.Weekdays, weekdayKey(t.Weekday())) {
+		return false
+	}
+
+	if !r.Yearly {
+		key := t.Format("2006-01-02")
+
+		return r.From <= key && key <= r.To
+	}
+
+	key := t.Format("01-02")
+	if r.From <= r.To {
+		return r.From <= key && key <= r.To
+	}
+
+	return key >= r.From || key <= r.To
+}
+
+// overlaps reports whether r and other share at least one day.
+func (r DateRange) overlaps(other DateRange) bool {
+	// ranges restricted to different weekdays never share a day
+	if len(r.Weekdays) > 0 && len(other.Weekdays) > 0 && !slices.ContainsFunc(r.Weekdays, func(d time.Weekday) bool {
+		return slices.Contains(other.Weekdays, d)
+	}) {
+		return false
+	}
+
+	// Yearly ranges are compared in every year covered by a fixed range.
+	// If both ranges are yearly, any year will do.
+	first, last := 2000, 2000
+	switch {
+	case !r.Yearly:
+		first, last = r.years()
+
+	case !other.Yearly:
+		first, last = other.years()
+	}
+
+	for year := first; year <= last; year++ {
+		for _, a := range r.intervals(year) {
+			for _, b := range other.intervals(year) {
+				if a[0] <= b[1] && b[0] <= a[1] {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// years returns the first and the last year of a fixed range.
+func (r DateRange) years() (int, int) {
+	from, err := time.Parse("2006-01-02", r.From)
+	if err != nil {
+		return 0, -1
+	}
+
+	to, err := time.Parse("2006-01-02", r.To)
+	if err != nil {
+		return 0, -1
+	}
+
+	return from.Year(), to.Year()
+}
+
+// intervals returns the periods (YYYY-MM-DD) covered by r around year. A
+// yearly range that spans the turn of the year covers the end of the
+// previous and the start of the following year as well.
+func (r DateRange) intervals(year int) [][2]string {
+	if !r.Yearly {
+		return [][2]string{{r.From, r.To}}
+	}
+
+	at := func(year int, date string) string {
+		return fmt.Sprintf("%04d-%s", year, date)
+	}
+
+	if r.From <= r.To {
+		return [][2]string{{at(year, r.From), at(year, r.To)}}
+	}
+
+	return [][2]string{
+		{at(year-1, r.From), at(year, r.To)},
+		{at(year, r.From), at(year+1, r.To)},
+	}
+}
+
+// OfficeHourModel is the database model of an office hour. It is also used
+// as the JSON representation of an office hour by the extension service.
+//
+// Note that DayOfWeek uses the numbering of commonv1.DayOfWeek so Sunday is
+// stored as 7.
 type OfficeHourModel struct {
-	ID               primitive.ObjectID              `bson:"_id"`
-	DayOfWeek        time.Weekday                    `bson:"dayOfWeek,omitempty"`
-	Date             string                          `bson:"date,omitempty"`
-	HolidayCondition office_hoursv1.HolidayCondition `bson:"holiday,omitempty"`
-	TimeRanges       []DayTimeRange                  `bson:"timeRanges"` // no omitempty!
+	ID               primitive.ObjectID              `bson:"_id" json:"name"`
+	Location         string                          `bson:"location,omitempty" json:"location,omitempty"`
+	DayOfWeek        time.Weekday                    `bson:"dayOfWeek,omitempty" json:"dayOfWeek,omitempty"`
+	Date             string                          `bson:"date,omitempty" json:"date,omitempty"`
+	HolidayCondition office_hoursv1.HolidayCondition `bson:"holiday,omitempty" json:"holidayCondition,omitempty"`
+	TimeRanges       []DayTimeRange                  `bson:"timeRanges" json:"timeRanges"` // no omitempty!
+
+	// DateRange may be set instead of DayOfWeek or Date to apply the office
+	// hour to each day of a period, for example during vacations. Without
+	// time ranges, the location is closed during the whole period.
+	DateRange *DateRange `bson:"dateRange,omitempty" json:"dateRange,omitempty"`
+
+	// ValidFrom and ValidUntil may be set to a date (YYYY-MM-DD) to limit
+	// the period in which the office hour is considered. Both dates are
+	// inclusive.
+	ValidFrom  string `bson:"validFrom,omitempty" json:"validFrom,omitempty"`
+	ValidUntil string `bson:"validUntil,omitempty" json:"validUntil,omitempty"`
+
+	// Revision is incremented whenever the office hour is modified. When
+	// upserting an office hour, a non-zero Revision must match the stored
+	// revision or ErrRevisionMismatch is returned. AnyRevision only
+	// requires the office hour to exist.
+	Revision int64 `bson:"revision" json:"revision,omitempty"`
+
+	// DeletedAt and DeletedBy are set once the office hour has been moved
+	// to the trash.
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	DeletedBy *Actor     `bson:"deletedBy,omitempty" json:"deletedBy,omitempty"`
+}
+
+// CopyExtendedFields copies all fields from other that cannot be represented
+// by the OfficeHour protobuf message.
+func (m *OfficeHourModel) CopyExtendedFields(other *OfficeHourModel) {
+	m.Location = other.Location
+	m.ValidFrom = other.ValidFrom
+	m.ValidUntil = other.ValidUntil
+
+	// date ranges are represented by their first day (see ToProto)
+	if other.DateRange != nil && m.Date == other.DateRange.From {
+		m.Date = ""
+		m.DateRange = other.DateRange
+	}
+
+	// keep the opening mode of all time ranges that still exist
+	for idx, tr := range m.TimeRanges {
+		for _, otr := range other.TimeRanges {
+			if tr.Start == otr.Start && tr.End == otr.End {
+				m.TimeRanges[idx].Mode = otr.Mode
+				break
+			}
+		}
+	}
+
+	// phone-only time ranges are not part of the protobuf message (see
+	// ToProto) so they are kept as well.
+	for _, otr := range other.TimeRanges {
+		if otr.Mode == ModePhoneOnly && !slices.ContainsFunc(m.TimeRanges, func(tr DayTimeRange) bool {
+			return tr.Start == otr.Start && tr.End == otr.End
+		}) {
+			m.TimeRanges = append(m.TimeRanges, otr)
+		}
+	}
+}
+
+// FieldViolation describes a single invalid field of an office hour.
+type FieldViolation struct {
+	// Field is the path of the invalid field using the JSON field names,
+	// for example "timeRanges[1].end".
+	Field string
+
+	// Description describes why the field is invalid.
+	Description string
+}
+
+// ValidationError is returned if an office hour contains semantic errors.
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for idx, v := range e.Violations {
+		msgs[idx] = v.Field + ": " + v.Description
+	}
+
+	return "invalid office hour: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) add(field string, format string, args ...any) {
+	e.Violations = append(e.Violations, FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate checks m for semantic errors. If m is invalid, a *ValidationError
+// describing all violations is returned.
+func (m *OfficeHourModel) Validate() error {
+	verr := new(ValidationError)
+
+	switch {
+	case m.DateRange != nil && (m.Date != "" || m.DayOfWeek != 0):
+		verr.add("dateRange", "dateRange is mutually exclusive with date and dayOfWeek")
+
+	case m.Date != "" && m.DayOfWeek != 0:
+		verr.add("date", "date and dayOfWeek are mutually exclusive")
+
+	case m.DateRange != nil:
+		layout, format := "2006-01-02", "YYYY-MM-DD"
+		if m.DateRange.Yearly {
+			layout, format = "01-02", "MM-DD"
+		}
+
+		valid := true
+		for _, v := range [][2]string{{"dateRange.from", m.DateRange.From}, {"dateRange.to", m.DateRange.To}} {
+			if _, err := time.Parse(layout, v[1]); err != nil {
+				verr.add(v[0], "invalid date %q, expected %s", v[1], format)
+				valid = false
+			}
+		}
+
+		// only yearly ranges may span the turn of the year
+		if valid && !m.DateRange.Yearly && m.DateRange.To < m.DateRange.From {
+			verr.add("dateRange.to", "dateRange.to must not be before dateRange.from")
+		}
+
+		for idx, d := range m.DateRange.Weekdays {
+			field := fmt.Sprintf("dateRange.weekdays[%d]", idx)
+
+			switch {
+			case d < 1 || d > 7:
+				verr.add(field, "invalid weekday %d", d)
+
+			case slices.Contains(m.DateRange.Weekdays[:idx], d):
+				verr.add(field, "duplicate weekday %d", d)
+			}
+		}
+
+	case m.Date != "":
+		if _, err := time.Parse("2006-01-02", m.Date); err != nil {
+			if _, err := time.Parse("01-02", m.Date); err != nil {
+				verr.add("date", "invalid date %q, expected YYYY-MM-DD or MM-DD", m.Date)
+			}
+		}
+
+	case m.DayOfWeek != 0:
+		if m.DayOfWeek < 1 || m.DayOfWeek > 7 {
+			verr.add("dayOfWeek", "invalid dayOfWeek %d", m.DayOfWeek)
+		}
+
+	default:
+		verr.add("kind", "either date, dayOfWeek or dateRange must be set")
+	}
+
+	// Only date specific office hours and date ranges may omit time ranges
+	// to mark the days as closed.
+	if len(m.TimeRanges) == 0 && m.Date == "" && m.DateRange == nil {
+		verr.add("timeRanges", "missing time ranges")
+	}
+
+	// only valid time ranges are checked for overlaps
+	var checked []int
+
+	for idx, tr := range m.TimeRanges {
+		field := fmt.Sprintf("timeRanges[%d]", idx)
+		valid := true
+
+		if !tr.Start.isValid() || tr.Start.Hours == 24 {
+			verr.add(field+".start", "invalid time %s", tr.Start)
+			valid = false
+		}
+
+		if !tr.End.isValid() {
+			verr.add(field+".end", "invalid time %s", tr.End)
+			valid = false
+		}
+
+		// Ranges that end before they start span midnight but a range must
+		// not be empty.
+		if tr.Start == tr.End {
+			verr.add(field, "start and end must not be equal")
+			valid = false
+		}
+
+		if !tr.Mode.IsValid() {
+			verr.add(field+".mode", "invalid opening mode %q", tr.Mode)
+		}
+
+		if !valid {
+			continue
+		}
+
+		for _, otherIdx := range checked {
+			if tr.overlaps(m.TimeRanges[otherIdx]) {
+				verr.add(field, "overlaps with timeRanges[%d]", otherIdx)
+			}
+		}
+
+		checked = append(checked, idx)
+	}
+
+	for _, v := range [][2]string{{"validFrom", m.ValidFrom}, {"validUntil", m.ValidUntil}} {
+		if v[1] == "" {
+			continue
+		}
+
+		if _, err := time.Parse("2006-01-02", v[1]); err != nil {
+			verr.add(v[0], "invalid validity date %q, expected YYYY-MM-DD", v[1])
+		}
+	}
+
+	if m.ValidFrom != "" && m.ValidUntil != "" && m.ValidUntil < m.ValidFrom {
+		verr.add("validUntil", "validUntil must not be before validFrom")
+	}
+
+	if len(verr.Violations) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// ValidateWith validates m and checks that it does not conflict with any of
+// others (see ConflictsWith).
+func (m *OfficeHourModel) ValidateWith(others []OfficeHourModel) error {
+	verr := new(ValidationError)
+	if err := m.Validate(); err != nil {
+		verr = err.(*ValidationError)
+	}
+
+	field := "date"
+	switch {
+	case m.DayOfWeek != 0:
+		field = "dayOfWeek"
+
+	case m.DateRange != nil:
+		field = "dateRange"
+	}
+
+	for idx := range others {
+		if m.ConflictsWith(&others[idx]) {
+			verr.add(field, "conflicts with office hour %s", others[idx].ID.Hex())
+		}
+	}
+
+	if len(verr.Violations) > 0 {
+		return verr
+	}
+
+	return nil
+}
+
+// ConflictsWith reports whether m and other compete for the same days. Two
+// office hours conflict if they belong to the same location, have the same
+// kind and holiday condition and their validity periods overlap. Date ranges
+// conflict if they share at least one day. In that case it would be
+// ambiguous which of them applies.
+func (m *OfficeHourModel) ConflictsWith(other *OfficeHourModel) bool {
+	if m.ID == other.ID ||
+		m.Location != other.Location ||
+		m.DayOfWeek != other.DayOfWeek ||
+		m.Date != other.Date ||
+		m.HolidayCondition != other.HolidayCondition {
+		return false
+	}
+
+	if (m.DateRange == nil) != (other.DateRange == nil) {
+		return false
+	}
+
+	if m.DateRange != nil && !m.DateRange.overlaps(*other.DateRange) {
+		return false
+	}
+
+	if m.ValidUntil != "" && other.ValidFrom != "" && m.ValidUntil < other.ValidFrom {
+		return false
+	}
+
+	if other.ValidUntil != "" && m.ValidFrom != "" && other.ValidUntil < m.ValidFrom {
+		return false
+	}
+
+	return true
+}
+
+// AppliesAt reports whether m is considered at the day of t. Holiday
+// conditions are not checked.
+func (m *OfficeHourModel) AppliesAt(t time.Time) bool {
+	dateKey := t.Format("2006-01-02")
+
+	if m.ValidFrom != "" && dateKey < m.ValidFrom {
+		return false
+	}
+
+	if m.ValidUntil != "" && dateKey > m.ValidUntil {
+		return false
+	}
+
+	switch {
+	case m.DayOfWeek != 0:
+		return m.DayOfWeek == weekdayKey(t.Weekday())
+
+	case m.Date != "":
+		return m.Date == dateKey || m.Date == t.Format("01-02")
+
+	case m.DateRange != nil:
+		return m.DateRange.Contains(t)
+	}
+
+	return false
+}
+
+// IsClosure reports whether m marks a date or a date range as closed the
+// whole day.
+func (m *OfficeHourModel) IsClosure() bool {
+	return (m.Date != "" || m.DateRange != nil) && len(m.TimeRanges) == 0
+}
+
+// Specificity returns how specific the kind of m is. Office hours with a
+// higher specificity take precedence over less specific ones.
+func (m *OfficeHourModel) Specificity() int {
+	switch {
+	case len(m.Date) == len("2006-01-02"):
+		return 4
+
+	case m.Date != "":
+		return 3
+
+	case m.DateRange != nil:
+		return 2
+
+	case m.DayOfWeek != 0:
+		return 1
+	}
+
+	return 0
+}
+
+// weekdayKey returns the value used to store d in OfficeHourModel.DayOfWeek.
+//
+// Office hours have always been stored with the commonv1.DayOfWeek numbering
+// (Sunday = 7, Sunday = 0 would be dropped by omitempty) but lookups used to
+// query time.Weekday (Sunday = 0) so Sunday office hours were never found.
+// Since the stored values are unchanged, no data migration is required for
+// this fix; existing Sunday office hours simply start to apply.
+func weekdayKey(d time.Weekday) time.Weekday {
+	if d == time.Sunday {
+		return time.Weekday(commonv1.DayOfWeek_SUNDAY)
+	}
+
+	return d
 }
 
 func (m OfficeHourModel) ToProto() *office_hoursv1.OfficeHour {
@@ -46,26 +619,32 @@ func (m OfficeHourModel) ToProto() *office_hoursv1.OfficeHour {
 		}
 
 	case m.Date != "":
-		date, err := commonv1.ParseDate(m.Date)
-		if err != nil {
-			t, err := time.Parse(m.Date, "01-02")
-			if err == nil {
-				date = &commonv1.Date{
-					Month: commonv1.FromMonth(t.Month()),
-					Day:   int32(t.Day()),
-				}
-			}
+		res.Kind = &office_hoursv1.OfficeHour_Date{
+			Date: dateToProto(m.Date),
 		}
 
+	case m.DateRange != nil:
+		// The OfficeHour message cannot represent date ranges so they are
+		// returned as their first day only, without their weekday
+		// restriction. Clients that need the whole range must use the
+		// ListExtendedOfficeHours RPC of the extension service.
 		res.Kind = &office_hoursv1.OfficeHour_Date{
-			Date: date,
+			Date: dateToProto(m.DateRange.From),
 		}
 	}
 
-	res.TimeRanges = make([]*commonv1.DayTimeRange, len(m.TimeRanges))
+	res.TimeRanges = make([]*commonv1.DayTimeRange, 0, len(m.TimeRanges))
 
-	for idx, tr := range m.TimeRanges {
-		res.TimeRanges[idx] = &commonv1.DayTimeRange{
+	for _, tr := range m.TimeRanges {
+		// The DayTimeRange message cannot represent opening modes and
+		// clients would treat phone-only time ranges as open so they are
+		// omitted. Opening modes are only available via the extension
+		// service.
+		if tr.Mode == ModePhoneOnly {
+			continue
+		}
+
+		res.TimeRanges = append(res.TimeRanges, &commonv1.DayTimeRange{
 			Start: &commonv1.DayTime{
 				Hour:   int32(tr.Start.Hours),
 				Minute: int32(tr.Start.Minutes),
@@ -76,12 +655,29 @@ func (m OfficeHourModel) ToProto() *office_hoursv1.OfficeHour {
 				Minute: int32(tr.End.Minutes),
 				Second: int32(tr.End.Seconds),
 			},
-		}
+		})
 	}
 
 	return res
 }
 
+// dateToProto converts a date formatted as YYYY-MM-DD or MM-DD to a
+// commonv1.Date.
+func dateToProto(s string) *commonv1.Date {
+	date, err := commonv1.ParseDate(s)
+	if err != nil {
+		t, err := time.Parse("01-02", s)
+		if err == nil {
+			date = &commonv1.Date{
+				Month: commonv1.FromMonth(t.Month()),
+				Day:   int32(t.Day()),
+			}
+		}
+	}
+
+	return date
+}
+
 func ModelFromProto(pb *office_hoursv1.OfficeHour) (*OfficeHourModel, error) {
 	var oid primitive.ObjectID
 
@@ -113,8 +709,10 @@ func ModelFromProto(pb *office_hoursv1.OfficeHour) (*OfficeHourModel, error) {
 		return nil, fmt.Errorf("missing OfficeHour.kind")
 	}
 
+	// Only date specific office hours may omit time ranges to mark the
+	// day as closed.
 	res.TimeRanges = make([]DayTimeRange, len(pb.TimeRanges))
-	if len(pb.TimeRanges) == 0 {
+	if len(pb.TimeRanges) == 0 && res.Date == "" {
 		return nil, fmt.Errorf("missing time ranges")
 	}
 
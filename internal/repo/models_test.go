@@ -0,0 +1,158 @@
+package repo
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProtoRoundTripKeepsPhoneOnlyRanges(t *testing.T) {
+	stored := &OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}, Mode: ModeAppointmentsOnly},
+			{Start: DayTime{Hours: 12}, End: DayTime{Hours: 18}, Mode: ModePhoneOnly},
+		},
+	}
+
+	pb := stored.ToProto()
+	if len(pb.TimeRanges) != 1 {
+		t.Fatalf("expected phone-only time ranges to be omitted, got %d time ranges", len(pb.TimeRanges))
+	}
+
+	model, err := ModelFromProto(pb)
+	if err != nil {
+		t.Fatalf("failed to convert protobuf message: %s", err)
+	}
+
+	model.CopyExtendedFields(stored)
+
+	if len(model.TimeRanges) != 2 {
+		t.Fatalf("expected two time ranges but got %d", len(model.TimeRanges))
+	}
+
+	for idx, tr := range model.TimeRanges {
+		if tr != stored.TimeRanges[idx] {
+			t.Errorf("expected time range %d to be %v but got %v", idx, stored.TimeRanges[idx], tr)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tr := func(start, end int) DayTimeRange {
+		return DayTimeRange{Start: DayTime{Hours: start}, End: DayTime{Hours: end}}
+	}
+
+	cases := []struct {
+		name   string
+		model  OfficeHourModel
+		fields []string
+	}{
+		{"valid", OfficeHourModel{DayOfWeek: time.Monday, TimeRanges: []DayTimeRange{tr(8, 12), tr(14, 18)}}, nil},
+		{"adjacent ranges", OfficeHourModel{DayOfWeek: time.Monday, TimeRanges: []DayTimeRange{tr(8, 12), tr(12, 18)}}, nil},
+		{"invalid time", OfficeHourModel{DayOfWeek: time.Monday, TimeRanges: []DayTimeRange{{Start: DayTime{Hours: 25, Minutes: 70}, End: DayTime{Hours: 12}}}}, []string{"timeRanges[0].start"}},
+		{"empty range", OfficeHourModel{DayOfWeek: time.Monday, TimeRanges: []DayTimeRange{tr(8, 8)}}, []string{"timeRanges[0]"}},
+		{"overlapping ranges", OfficeHourModel{DayOfWeek: time.Monday, TimeRanges: []DayTimeRange{tr(8, 12), tr(11, 14)}}, []string{"timeRanges[1]"}},
+		{"overlap across midnight", OfficeHourModel{DayOfWeek: time.Friday, TimeRanges: []DayTimeRange{tr(22, 2), tr(1, 3)}}, []string{"timeRanges[1]"}},
+		{"no overlap across midnight", OfficeHourModel{DayOfWeek: time.Friday, TimeRanges: []DayTimeRange{tr(22, 2), tr(3, 5)}}, nil},
+		{"missing kind", OfficeHourModel{TimeRanges: []DayTimeRange{tr(8, 12)}}, []string{"kind"}},
+		{"invalid weekday", OfficeHourModel{DayOfWeek: 8, TimeRanges: []DayTimeRange{tr(8, 12)}}, []string{"dayOfWeek"}},
+		{"date and weekday", OfficeHourModel{Date: "2024-12-24", DayOfWeek: time.Monday}, []string{"date"}},
+		{"reversed date range", OfficeHourModel{DateRange: &DateRange{From: "2024-12-31", To: "2024-12-24"}}, []string{"dateRange.to"}},
+		{"date range weekdays", OfficeHourModel{DateRange: &DateRange{From: "2024-07-01", To: "2024-08-31", Weekdays: []time.Weekday{1, 2, 7}}}, nil},
+		{"invalid date range weekdays", OfficeHourModel{DateRange: &DateRange{From: "2024-07-01", To: "2024-08-31", Weekdays: []time.Weekday{0, 1, 1}}}, []string{"dateRange.weekdays[0]", "dateRange.weekdays[2]"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assertViolations(t, c.model.Validate(), c.fields)
+		})
+	}
+}
+
+func TestValidateWithDetectsConflicts(t *testing.T) {
+	monday := OfficeHourModel{
+		ID:         primitive.NewObjectID(),
+		DayOfWeek:  time.Monday,
+		TimeRanges: []DayTimeRange{{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}}},
+	}
+
+	cases := []struct {
+		name   string
+		model  OfficeHourModel
+		fields []string
+	}{
+		{"duplicate weekday", OfficeHourModel{DayOfWeek: time.Monday, TimeRanges: monday.TimeRanges}, []string{"dayOfWeek"}},
+		{"other weekday", OfficeHourModel{DayOfWeek: time.Tuesday, TimeRanges: monday.TimeRanges}, nil},
+		{"other location", OfficeHourModel{DayOfWeek: time.Monday, Location: "branch", TimeRanges: monday.TimeRanges}, nil},
+		{"same document", OfficeHourModel{ID: monday.ID, DayOfWeek: time.Monday, TimeRanges: monday.TimeRanges}, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assertViolations(t, c.model.ValidateWith([]OfficeHourModel{monday}), c.fields)
+		})
+	}
+}
+
+func assertViolations(t *testing.T, err error, fields []string) {
+	t.Helper()
+
+	if len(fields) == 0 {
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		return
+	}
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error but got %v", err)
+	}
+
+	got := make([]string, len(verr.Violations))
+	for idx, v := range verr.Violations {
+		got[idx] = v.Field
+	}
+
+	if !slices.Equal(got, fields) {
+		t.Errorf("expected violations for %v but got %v", fields, got)
+	}
+}
+
+func TestDateRangeWeekdays(t *testing.T) {
+	weekdays := DateRange{From: "2024-07-01", To: "2024-08-31", Weekdays: []time.Weekday{1, 2, 3, 4, 5}}
+	weekends := DateRange{From: "2024-08-01", To: "2024-09-30", Weekdays: []time.Weekday{6, 7}}
+
+	cases := []struct {
+		date     time.Time
+		expected bool
+	}{
+		{time.Date(2024, time.July, 5, 0, 0, 0, 0, time.UTC), true},       // Friday
+		{time.Date(2024, time.July, 6, 0, 0, 0, 0, time.UTC), false},      // Saturday
+		{time.Date(2024, time.July, 7, 0, 0, 0, 0, time.UTC), false},      // Sunday
+		{time.Date(2024, time.September, 2, 0, 0, 0, 0, time.UTC), false}, // Monday after the range
+	}
+
+	for _, c := range cases {
+		if got := weekdays.Contains(c.date); got != c.expected {
+			t.Errorf("expected Contains(%s) to be %t", c.date.Format("2006-01-02"), c.expected)
+		}
+	}
+
+	if !weekends.Contains(time.Date(2024, time.August, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected Sunday to be part of the weekend range")
+	}
+
+	if weekdays.overlaps(weekends) {
+		t.Errorf("expected ranges on different weekdays to not overlap")
+	}
+
+	if !weekdays.overlaps(DateRange{From: "2024-08-01", To: "2024-08-31"}) {
+		t.Errorf("expected a range without weekdays to overlap")
+	}
+}
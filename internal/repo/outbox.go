@@ -0,0 +1,152 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// OutboxEntry is an event that is waiting to be published.
+type OutboxEntry struct {
+	// Key is the idempotency key of the event. Events with the same key
+	// are only enqueued once.
+	Key string `bson:"_id" json:"key"`
+
+	// Event holds the binary encoded anypb.Any of the event.
+	Event []byte `bson:"event" json:"event"`
+
+	CreatedAt   time.Time `bson:"createdAt" json:"createdAt"`
+	NextAttempt time.Time `bson:"nextAttempt" json:"nextAttempt"`
+	Attempts    int       `bson:"attempts" json:"attempts"`
+	LastError   string    `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	DeliveredAt time.Time `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+
+	// DeadAt is set once delivering the event has been given up. Dead
+	// events are not delivered anymore.
+	DeadAt time.Time `bson:"deadAt,omitempty" json:"deadAt,omitempty"`
+}
+
+// Any returns the event stored in e.
+func (e OutboxEntry) Any() (*anypb.Any, error) {
+	var pb anypb.Any
+	if err := proto.Unmarshal(e.Event, &pb); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal outbox event %q: %w", e.Key, err)
+	}
+
+	return &pb, nil
+}
+
+// EnqueueEvent stores msg in the outbox using the idempotency key. If an
+// event with the same key has already been enqueued, EnqueueEvent is a
+// no-op.
+func (r *Repo) EnqueueEvent(ctx context.Context, key string, msg proto.Message) error {
+	entry, err := newOutboxEntry(key, msg)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.outbox.InsertOne(ctx, entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to enqueue event: %w", err)
+	}
+
+	return nil
+}
+
+// newOutboxEntry returns a new outbox entry for msg that is due immediately.
+// If msg already is an *anypb.Any, it is stored as is so events may use
+// their own type URL.
+func newOutboxEntry(key string, msg proto.Message) (OutboxEntry, error) {
+	pb, ok := msg.(*anypb.Any)
+	if !ok {
+		var err error
+		if pb, err = anypb.New(msg); err != nil {
+			return OutboxEntry{}, err
+		}
+	}
+
+	blob, err := proto.Marshal(pb)
+	if err != nil {
+		return OutboxEntry{}, err
+	}
+
+	now := time.Now()
+
+	return OutboxEntry{
+		Key:         key,
+		Event:       blob,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}, nil
+}
+
+// PendingEvents returns all events that have neither been delivered nor
+// given up yet in the order they have been enqueued.
+func (r *Repo) PendingEvents(ctx context.Context) ([]OutboxEntry, error) {
+	res, err := r.outbox.Find(ctx, bson.M{
+		"deliveredAt": bson.M{"$exists": false},
+		"deadAt":      bson.M{"$exists": false},
+	}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []OutboxEntry
+	if err := res.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode outbox entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// MarkEventDelivered marks the event with the given key as delivered.
+func (r *Repo) MarkEventDelivered(ctx context.Context, key string) error {
+	_, err := r.outbox.UpdateByID(ctx, key, bson.M{
+		"$set": bson.M{
+			"deliveredAt": time.Now(),
+		},
+	})
+
+	return err
+}
+
+// MarkEventFailed records a failed delivery attempt for the event with the
+// given key and schedules the next attempt.
+func (r *Repo) MarkEventFailed(ctx context.Context, key string, nextAttempt time.Time, deliveryErr error) error {
+	_, err := r.outbox.UpdateByID(ctx, key, bson.M{
+		"$set": bson.M{
+			"nextAttempt": nextAttempt,
+			"lastError":   deliveryErr.Error(),
+		},
+		"$inc": bson.M{
+			"attempts": 1,
+		},
+	})
+
+	return err
+}
+
+// MarkEventDead records the final failed delivery attempt for the event with
+// the given key. The event is not delivered anymore.
+func (r *Repo) MarkEventDead(ctx context.Context, key string, deliveryErr error) error {
+	_, err := r.outbox.UpdateByID(ctx, key, bson.M{
+		"$set": bson.M{
+			"deadAt":    time.Now(),
+			"lastError": deliveryErr.Error(),
+		},
+		"$inc": bson.M{
+			"attempts": 1,
+		},
+	})
+
+	return err
+}
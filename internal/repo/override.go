@@ -0,0 +1,176 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// overrideRetention is how long overrides are kept after they ended.
+const overrideRetention = 7 * 24 * time.Hour
+
+// Override forces a location to be open or closed regardless of its office
+// hours. There is at most one override per location.
+type Override struct {
+	// Location is the location the override applies to.
+	Location string `bson:"_id" json:"location"`
+
+	// Open is true if the location is forced open and false if it is forced
+	// closed.
+	Open bool `bson:"open" json:"open"`
+
+	// Mode is the opening mode that applies while the location is forced
+	// open. A location that is forced closed may use ModePhoneOnly to be
+	// reported as reachable by phone.
+	Mode OpeningMode `bson:"mode,omitempty" json:"mode,omitempty"`
+
+	// Until is the time at which the override expires. If nil, the
+	// override is active until it is revoked. Revoking an override sets
+	// Until to the time of revocation.
+	Until *time.Time `bson:"until,omitempty" json:"until,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// ActiveAt reports whether o applies at t.
+func (o *Override) ActiveAt(t time.Time) bool {
+	if t.Before(o.CreatedAt) {
+		return false
+	}
+
+	return o.Until == nil || t.Before(*o.Until)
+}
+
+// OpeningMode returns the opening mode that applies while o is active.
+func (o *Override) OpeningMode() OpeningMode {
+	if !o.Open {
+		return o.Mode
+	}
+
+	if o.Mode == "" {
+		return ModeConsultation
+	}
+
+	return o.Mode
+}
+
+// Apply returns the open ranges between from and to after applying o to
+// ranges.
+func (o *Override) Apply(ranges []TimeRange, from, to time.Time) []TimeRange {
+	start := o.CreatedAt
+	if start.Before(from) {
+		start = from
+	}
+
+	end := to
+	if o.Until != nil && o.Until.Before(end) {
+		end = *o.Until
+	}
+
+	if !end.After(start) {
+		return ranges
+	}
+
+	var result []TimeRange
+	for _, tr := range ranges {
+		// keep the parts of tr that are not covered by the override
+		if tr.From.Before(start) {
+			result = append(result, TimeRange{From: tr.From, To: minTime(tr.To, start)})
+		}
+
+		if tr.To.After(end) {
+			result = append(result, TimeRange{From: maxTime(tr.From, end), To: tr.To})
+		}
+	}
+
+	if o.Open {
+		result = append(result, TimeRange{From: start, To: end})
+
+		slices.SortFunc(result, func(a, b TimeRange) int {
+			return a.From.Compare(b.From)
+		})
+	}
+
+	return result
+}
+
+// SetOverride creates or replaces the override of o.Location.
+func (r *Repo) SetOverride(ctx context.Context, o *Override) error {
+	if _, err := r.overrides.ReplaceOne(ctx, bson.M{"_id": o.Location}, o, options.Replace().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to store override: %w", err)
+	}
+
+	r.invalidateCache()
+
+	return nil
+}
+
+// GetOverride returns the most recent override of location or nil if there
+// is none. Overrides that ended are kept for overrideRetention so callers
+// must check whether the override is still active.
+func (r *Repo) GetOverride(ctx context.Context, location string) (*Override, error) {
+	if o, ok := r.cachedOverride(location); ok {
+		return o, nil
+	}
+
+	var o Override
+	if err := r.overrides.FindOne(ctx, bson.M{"_id": location}).Decode(&o); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed to load override: %w", err)
+	}
+
+	return &o, nil
+}
+
+// RevokeOverride ends the active override of location at t. The override
+// is kept so the resolver knows when the regular office hours applied
+// again.
+func (r *Repo) RevokeOverride(ctx context.Context, location string, t time.Time) error {
+	res, err := r.overrides.UpdateOne(ctx, bson.M{
+		"_id": location,
+		"$or": bson.A{
+			bson.M{"until": bson.M{"$exists": false}},
+			bson.M{"until": bson.M{"$gt": t}},
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"until": t,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke override: %w", err)
+	}
+
+	r.invalidateCache()
+
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+
+	return b
+}
@@ -16,11 +16,43 @@ import (
 
 var ErrNotFound = errors.New("office-hour not found")
 
+// ErrRevisionMismatch is returned if an office hour has been modified since
+// the expected revision.
+var ErrRevisionMismatch = errors.New("office-hour has been modified concurrently")
+
+// AnyRevision may be set as the revision of an upserted office hour to
+// require that the office hour already exists, regardless of its revision.
+// ErrNotFound is returned if it does not exist.
+const AnyRevision int64 = -1
+
 type Repo struct {
-	col *mongo.Collection
+	client    *mongo.Client
+	col       *mongo.Collection
+	outbox    *mongo.Collection
+	leases    *mongo.Collection
+	overrides *mongo.Collection
+	audit     *mongo.Collection
+	versions  *mongo.Collection
+	drafts    *mongo.Collection
+	counters  *mongo.Collection
+
+	// transactions is set if the database supports multi-document
+	// transactions.
+	transactions bool
+
+	// versionRetention is the number of schedule versions kept. Zero keeps
+	// all versions.
+	versionRetention int
+
+	// cache is only valid while the change stream opened by WatchChanges
+	// is healthy.
+	cache *changeCache
 }
 
-func NewRepo(ctx context.Context, url string, db string) (*Repo, error) {
+// NewRepo connects to the MongoDB server at url and returns a Repo that uses
+// the database db. Unless allowNonAtomic is set, the server must support
+// multi-document transactions.
+func NewRepo(ctx context.Context, url string, db string, allowNonAtomic bool) (*Repo, error) {
 	clientOptions := options.Client().ApplyURI(url)
 
 	cli, err := mongo.Connect(ctx, clientOptions)
@@ -29,87 +61,387 @@ func NewRepo(ctx context.Context, url string, db string) (*Repo, error) {
 	}
 
 	r := &Repo{
-		col: cli.Database(db).Collection("office-hours"),
+		client:    cli,
+		col:       cli.Database(db).Collection("office-hours"),
+		outbox:    cli.Database(db).Collection("office-hours-outbox"),
+		leases:    cli.Database(db).Collection("office-hours-leases"),
+		overrides: cli.Database(db).Collection("office-hours-overrides"),
+		audit:     cli.Database(db).Collection("office-hours-audit"),
+		versions:  cli.Database(db).Collection("office-hours-versions"),
+		drafts:    cli.Database(db).Collection("office-hours-drafts"),
+		counters:  cli.Database(db).Collection("office-hours-counters"),
+		cache:     new(changeCache),
+	}
+
+	if err := r.setup(ctx, allowNonAtomic); err != nil {
+		return nil, err
 	}
 
 	return r, nil
 }
 
-func (r *Repo) UpsertOfficeHours(ctx context.Context, pb *office_hoursv1.OfficeHour) (*office_hoursv1.OfficeHour, error) {
-	model, err := ModelFromProto(pb)
-	if err != nil {
-		return nil, err
+// SetVersionRetention configures how many schedule versions are kept. Since
+// every change stores a copy of the whole schedule, older versions are
+// removed once n versions exist. Zero, the default, keeps all versions.
+func (r *Repo) SetVersionRetention(n int) {
+	r.versionRetention = n
+}
+
+func (r *Repo) setup(ctx context.Context, allowNonAtomic bool) error {
+	if err := r.detectTransactions(ctx, allowNonAtomic); err != nil {
+		return err
+	}
+
+	if err := r.setupVersionCounter(ctx); err != nil {
+		return err
+	}
+
+	if _, err := r.outbox.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "deliveredAt", Value: 1},
+				{Key: "nextAttempt", Value: 1},
+			},
+		},
+		{
+			// keep delivered events for some time so duplicate events
+			// are still detected.
+			Keys:    bson.D{{Key: "deliveredAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32((7 * 24 * time.Hour).Seconds())).SetName("deliveredAt_ttl"),
+		},
+		{
+			// keep dead events for some time so they can be inspected.
+			Keys:    bson.D{{Key: "deadAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32((30 * 24 * time.Hour).Seconds())).SetName("deadAt_ttl"),
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to create outbox indexes: %w", err)
+	}
+
+	// remove overrides automatically some time after they ended
+	if _, err := r.overrides.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "until", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(overrideRetention.Seconds())).SetName("until_ttl"),
+	}); err != nil {
+		return fmt.Errorf("failed to create override indexes: %w", err)
+	}
+
+	// FindByTime and FindBetween query one $or branch per kind of office
+	// hour so each branch needs its own index.
+	if _, err := r.col.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "deletedAt", Value: 1}},
+		},
+		{
+			Keys: bson.D{
+				{Key: "location", Value: 1},
+				{Key: "date", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "location", Value: 1},
+				{Key: "dayOfWeek", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "location", Value: 1},
+				{Key: "dateRange.yearly", Value: 1},
+				{Key: "dateRange.from", Value: 1},
+				{Key: "dateRange.to", Value: 1},
+			},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to create office-hour indexes: %w", err)
+	}
+
+	if _, err := r.audit.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "officeHour", Value: 1},
+			{Key: "timestamp", Value: -1},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to create audit indexes: %w", err)
 	}
 
+	return nil
+}
+
+// UpsertOfficeHours creates or replaces the office hour model. If model does
+// not have an ID yet, a new one is assigned. If model.Revision is set, the
+// stored office hour must still have that revision or ErrRevisionMismatch is
+// returned. If it is AnyRevision, the office hour must exist or ErrNotFound
+// is returned. The mutation is recorded in the audit log using the actor from
+// ctx in the same transaction.
+func (r *Repo) UpsertOfficeHours(ctx context.Context, model *OfficeHourModel) (*OfficeHourModel, error) {
 	if model.ID.IsZero() {
 		model.ID = primitive.NewObjectIDFromTimestamp(time.Now())
 	}
 
-	replaceOptions := options.FindOneAndReplace().
-		SetUpsert(true).
-		SetReturnDocument(options.After)
+	expected := model.Revision
+
+	// upserting an office hour that is in the trash restores it
+	model.DeletedAt = nil
+	model.DeletedBy = nil
+
+	err := r.withTransaction(ctx, func(ctx context.Context) error {
+		previous := new(OfficeHourModel)
+		switch err := r.col.FindOne(ctx, bson.M{"_id": model.ID}).Decode(previous); {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			// the office hour will be created
+			previous = nil
+
+		case err != nil:
+			return fmt.Errorf("failed to load previous office-hour document: %w", err)
+		}
+
+		var current int64
+		if previous != nil {
+			current = previous.Revision
+		}
+
+		switch {
+		case expected == AnyRevision:
+			if previous == nil {
+				return ErrNotFound
+			}
+
+		case expected != 0 && expected != current:
+			return ErrRevisionMismatch
+		}
+
+		model.Revision = current + 1
 
-	res := r.col.FindOneAndReplace(ctx, bson.M{
-		"_id": model.ID,
-	}, model, replaceOptions)
+		// The write is guarded by the revision as well so concurrent
+		// modifications are detected even if the database does not
+		// support transactions.
+		if previous == nil {
+			if _, err := r.col.InsertOne(ctx, model); err != nil {
+				if mongo.IsDuplicateKeyError(err) {
+					return ErrRevisionMismatch
+				}
 
-	if res.Err() != nil {
-		return nil, fmt.Errorf("failed to perform findAndReplace operation: %w", err)
+				return fmt.Errorf("failed to insert office-hour document: %w", err)
+			}
+		} else {
+			res, err := r.col.ReplaceOne(ctx, bson.M{"_id": model.ID, "revision": current}, model)
+			if err != nil {
+				return fmt.Errorf("failed to replace office-hour document: %w", err)
+			}
+
+			if res.MatchedCount == 0 {
+				return ErrRevisionMismatch
+			}
+		}
+
+		if err := r.recordAudit(ctx, AuditActionUpsert, model.ID.Hex(), previous, model); err != nil {
+			return err
+		}
+
+		_, err := r.snapshot(ctx, "upsert "+model.ID.Hex())
+
+		return err
+	})
+
+	r.invalidateCache()
+
+	if err != nil {
+		return nil, err
 	}
 
-	var newModel OfficeHourModel
+	return model, nil
+}
 
-	if err := res.Decode(&newModel); err != nil {
-		return nil, fmt.Errorf("failed to decode new office-hour document: %w", err)
+// ListOfficeHours returns all office hours. If location is set, only office
+// hours of that location are returned.
+func (r *Repo) ListOfficeHours(ctx context.Context, location string) ([]OfficeHourModel, error) {
+	filter := bson.M{}
+	if location != "" {
+		filter = locationFilter(location)
 	}
 
-	return newModel.ToProto(), nil
+	return r.findModels(ctx, filter)
 }
 
-func (r *Repo) ListOfficeHours(ctx context.Context) ([]*office_hoursv1.OfficeHour, error) {
-	return r.find(ctx, bson.M{})
+// GetOfficeHour returns the office hour model identified by name.
+func (r *Repo) GetOfficeHour(ctx context.Context, name string) (*OfficeHourModel, error) {
+	oid, err := primitive.ObjectIDFromHex(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid office-hour name: %w", err)
+	}
+
+	var model OfficeHourModel
+	if err := r.col.FindOne(ctx, bson.M{"_id": oid, "deletedAt": notDeleted}).Decode(&model); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	return &model, nil
+}
+
+// ListLocations returns all locations that have office hours assigned. The
+// default location (an empty string) is always part of the result.
+func (r *Repo) ListLocations(ctx context.Context) ([]string, error) {
+	values, err := r.col.Distinct(ctx, "location", bson.M{"deletedAt": notDeleted})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query distinct locations: %w", err)
+	}
+
+	locations := []string{""}
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			locations = append(locations, s)
+		}
+	}
+
+	return locations, nil
 }
 
+// DeleteOfficeHour moves the office hour identified by name to the trash.
+// The mutation is recorded in the audit log using the actor from ctx in the
+// same transaction.
 func (r *Repo) DeleteOfficeHour(ctx context.Context, name string) error {
 	oid, err := primitive.ObjectIDFromHex(name)
 	if err != nil {
 		return fmt.Errorf("invalid office-hour name: %w", err)
 	}
 
-	res, err := r.col.DeleteOne(ctx, bson.M{"_id": oid})
-	if err != nil {
-		if errors.Is(err, mongo.ErrNoDocuments) {
-			return ErrNotFound
+	actor := ActorFrom(ctx)
+
+	err = r.withTransaction(ctx, func(ctx context.Context) error {
+		var previous OfficeHourModel
+		if err := r.col.FindOneAndUpdate(ctx, bson.M{
+			"_id":       oid,
+			"deletedAt": notDeleted,
+		}, bson.M{
+			"$set": bson.M{
+				"deletedAt": time.Now(),
+				"deletedBy": actor,
+			},
+			"$inc": bson.M{
+				"revision": 1,
+			},
+		}).Decode(&previous); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return ErrNotFound
+			}
+
+			return err
+		}
+
+		if err := r.recordAudit(ctx, AuditActionDelete, name, &previous, nil); err != nil {
+			return err
 		}
 
+		_, err := r.snapshot(ctx, "delete "+name)
+
 		return err
+	})
+
+	r.invalidateCache()
+
+	return err
+}
+
+// FindByTime returns all office hours of location that might apply at t.
+// Office hours that are not valid at the day of t are not returned.
+//
+// Yearly date ranges that span the turn of the year and weekday restrictions
+// cannot be matched using an index so all yearly date ranges are loaded and
+// filtered afterwards.
+func (r *Repo) FindByTime(ctx context.Context, t time.Time, location string) ([]OfficeHourModel, error) {
+	if hours, ok := r.cachedSchedule(); ok {
+		return hours.FindByTime(ctx, t, location)
 	}
 
-	if res.DeletedCount == 0 {
-		return ErrNotFound
+	dateKey := t.Format("2006-01-02")
+
+	filter := locationFilter(location)
+	filter["$and"] = validityFilter(dateKey, dateKey)
+	filter["$or"] = bson.A{
+		bson.M{
+			"date": bson.M{
+				"$in": bson.A{
+					t.Format("01-02"),
+					t.Format("2006-01-02"),
+				},
+			},
+		},
+		bson.M{
+			"dayOfWeek": weekdayKey(t.Weekday()),
+		},
+		dateRangeFilter(dateKey, dateKey),
+		bson.M{
+			"dateRange.yearly": true,
+		},
 	}
 
-	return nil
+	hours, err := r.findModels(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return Schedule(hours).FindByTime(ctx, t, location)
 }
 
-func (r *Repo) FindByTime(ctx context.Context, t time.Time) ([]*office_hoursv1.OfficeHour, error) {
-	filter := bson.M{
-		"$or": bson.A{
-			bson.M{
-				"date": bson.M{
-					"$in": bson.A{
-						t.Format("01-02"),
-						t.Format("2006-01-02"),
-					},
-				},
+// FindBetween returns all office hours of location that might apply at any
+// day between from and to (both inclusive). Yearly date ranges are always
+// returned.
+func (r *Repo) FindBetween(ctx context.Context, from, to time.Time, location string) ([]OfficeHourModel, error) {
+	if hours, ok := r.cachedSchedule(); ok {
+		return hours.FindBetween(ctx, from, to, location)
+	}
+
+	var (
+		dates    = bson.A{}
+		weekdays = bson.A{}
+		seen     = make(map[time.Weekday]struct{}, 7)
+	)
+
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d.Format("01-02"), d.Format("2006-01-02"))
+
+		if _, ok := seen[d.Weekday()]; !ok {
+			seen[d.Weekday()] = struct{}{}
+			weekdays = append(weekdays, weekdayKey(d.Weekday()))
+		}
+	}
+
+	filter := locationFilter(location)
+	filter["$and"] = validityFilter(from.Format("2006-01-02"), to.Format("2006-01-02"))
+	filter["$or"] = bson.A{
+		bson.M{
+			"date": bson.M{
+				"$in": dates,
 			},
-			bson.M{
-				"dayOfWeek": t.Weekday(),
+		},
+		bson.M{
+			"dayOfWeek": bson.M{
+				"$in": weekdays,
 			},
 		},
+		dateRangeFilter(from.Format("2006-01-02"), to.Format("2006-01-02")),
+		bson.M{
+			"dateRange.yearly": true,
+		},
 	}
 
-	return r.find(ctx, filter)
+	return r.findModels(ctx, filter)
+}
+
+// dateRangeFilter returns a filter that matches all office hours with a
+// non-recurring date range that shares at least one day with the dates
+// first and last (both formatted as YYYY-MM-DD).
+func dateRangeFilter(first, last string) bson.M {
+	return bson.M{
+		"dateRange.yearly": bson.M{"$ne": true},
+		"dateRange.from":   bson.M{"$lte": last},
+		"dateRange.to":     bson.M{"$gte": first},
+	}
 }
 
 func (r *Repo) FindByDate(ctx context.Context, date *commonv1.Date) ([]*office_hoursv1.OfficeHour, error) {
@@ -125,11 +457,49 @@ func (r *Repo) FindByDate(ctx context.Context, date *commonv1.Date) ([]*office_h
 
 func (r *Repo) FindByWeekday(ctx context.Context, weekday time.Weekday) ([]*office_hoursv1.OfficeHour, error) {
 	return r.find(ctx, bson.M{
-		"dayOfWeek": weekday,
+		"dayOfWeek": weekdayKey(weekday),
 	})
 }
 
-func (r *Repo) find(ctx context.Context, filter bson.M) ([]*office_hoursv1.OfficeHour, error) {
+// locationFilter returns a filter that matches all documents of location.
+// Documents without a location belong to the default location.
+func locationFilter(location string) bson.M {
+	if location == "" {
+		return bson.M{"location": bson.M{"$exists": false}}
+	}
+
+	return bson.M{"location": location}
+}
+
+// validityFilter returns filter conditions that match all documents that
+// are valid at least at one day between the dates first and last (both
+// formatted as YYYY-MM-DD).
+func validityFilter(first, last string) bson.A {
+	return bson.A{
+		bson.M{
+			"$or": bson.A{
+				bson.M{"validFrom": bson.M{"$exists": false}},
+				bson.M{"validFrom": bson.M{"$lte": last}},
+			},
+		},
+		bson.M{
+			"$or": bson.A{
+				bson.M{"validUntil": bson.M{"$exists": false}},
+				bson.M{"validUntil": bson.M{"$gte": first}},
+			},
+		},
+	}
+}
+
+// notDeleted matches all office hours that are not in the trash when used
+// as the condition of the deletedAt field.
+var notDeleted = bson.M{"$exists": false}
+
+// findModels returns all office hours that match filter and are not in the
+// trash.
+func (r *Repo) findModels(ctx context.Context, filter bson.M) ([]OfficeHourModel, error) {
+	filter["deletedAt"] = notDeleted
+
 	res, err := r.col.Find(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -141,6 +511,15 @@ func (r *Repo) find(ctx context.Context, filter bson.M) ([]*office_hoursv1.Offic
 		return nil, fmt.Errorf("failed to decode office-hour documents: %w", err)
 	}
 
+	return models, nil
+}
+
+func (r *Repo) find(ctx context.Context, filter bson.M) ([]*office_hoursv1.OfficeHour, error) {
+	models, err := r.findModels(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
 	pbRes := make([]*office_hoursv1.OfficeHour, len(models))
 	for idx, m := range models {
 		pbRes[idx] = m.ToProto()
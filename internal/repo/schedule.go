@@ -0,0 +1,66 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schedule is an in-memory set of office hours. It implements the same
+// lookups as Repo so a schedule that is not live (like a draft) can be
+// resolved as well.
+type Schedule []OfficeHourModel
+
+// Validate checks all office hours of s for semantic errors and conflicts
+// between each other (see ScheduleDocument.Validate). Changes that replace
+// the whole live schedule validate the resulting schedule in the same
+// transaction so no conflicting office hours are stored.
+func (s Schedule) Validate() error {
+	return (&ScheduleDocument{OfficeHours: s}).Validate()
+}
+
+// FindByTime returns all office hours of location that might apply at t.
+func (s Schedule) FindByTime(_ context.Context, t time.Time, location string) ([]OfficeHourModel, error) {
+	var result []OfficeHourModel
+
+	for _, m := range s {
+		if m.Location == location && m.AppliesAt(t) {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
+
+// FindBetween returns all office hours of location that might apply at any
+// day between from and to (both inclusive).
+func (s Schedule) FindBetween(_ context.Context, from, to time.Time, location string) ([]OfficeHourModel, error) {
+	var result []OfficeHourModel
+
+	for _, m := range s {
+		if m.Location != location {
+			continue
+		}
+
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			if m.AppliesAt(d) {
+				result = append(result, m)
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Sorted returns a copy of s sorted by name.
+func (s Schedule) Sorted() Schedule {
+	sorted := slices.Clone(s)
+
+	slices.SortFunc(sorted, func(a, b OfficeHourModel) int {
+		return strings.Compare(a.ID.Hex(), b.ID.Hex())
+	})
+
+	return sorted
+}
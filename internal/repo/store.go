@@ -0,0 +1,148 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// OfficeHourStore stores office hours and all related state of the service.
+//
+// Repo implements OfficeHourStore using MongoDB. MemoryStore and FileStore
+// may be used by small installations and tests that should run without a
+// MongoDB server.
+type OfficeHourStore interface {
+	// UpsertOfficeHours creates or replaces the office hour model. If model
+	// does not have an ID yet, a new one is assigned.
+	UpsertOfficeHours(ctx context.Context, model *OfficeHourModel) (*OfficeHourModel, error)
+
+	// ListOfficeHours returns all office hours. If location is set, only
+	// office hours of that location are returned.
+	ListOfficeHours(ctx context.Context, location string) ([]OfficeHourModel, error)
+
+	// GetOfficeHour returns the office hour identified by name or
+	// ErrNotFound.
+	GetOfficeHour(ctx context.Context, name string) (*OfficeHourModel, error)
+
+	// ListLocations returns all locations that have office hours assigned,
+	// including the default location.
+	ListLocations(ctx context.Context) ([]string, error)
+
+	// DeleteOfficeHour moves the office hour identified by name to the
+	// trash or returns ErrNotFound.
+	DeleteOfficeHour(ctx context.Context, name string) error
+
+	// ListDeletedOfficeHours returns all office hours in the trash, most
+	// recently deleted ones first. If location is set, only office hours of
+	// that location are returned.
+	ListDeletedOfficeHours(ctx context.Context, location string) ([]OfficeHourModel, error)
+
+	// RestoreOfficeHour moves an office hour out of the trash or returns
+	// ErrNotFound. A *ValidationError is returned if the office hour
+	// conflicts with the live schedule.
+	RestoreOfficeHour(ctx context.Context, name string) (*OfficeHourModel, error)
+
+	// PurgeDeletedOfficeHours permanently deletes all office hours that
+	// have been moved to the trash before t.
+	PurgeDeletedOfficeHours(ctx context.Context, t time.Time) (int, error)
+
+	// FindByTime returns all office hours of location that might apply at t.
+	FindByTime(ctx context.Context, t time.Time, location string) ([]OfficeHourModel, error)
+
+	// FindBetween returns all office hours of location that might apply at
+	// any day between from and to (both inclusive).
+	FindBetween(ctx context.Context, from, to time.Time, location string) ([]OfficeHourModel, error)
+
+	// ListAuditEntries returns the audit trail of an office hour, most
+	// recent entries first.
+	ListAuditEntries(ctx context.Context, name string) ([]AuditEntry, error)
+
+	// ListVersions returns all retained schedule versions (see
+	// SetVersionRetention) without their office hours, most recent
+	// versions first.
+	ListVersions(ctx context.Context) ([]ScheduleVersion, error)
+
+	// GetVersion returns a schedule version or ErrVersionNotFound.
+	GetVersion(ctx context.Context, version int64) (*ScheduleVersion, error)
+
+	// RollbackToVersion restores all office hours to the state of version
+	// and returns the new version. It returns a *ValidationError if the
+	// restored schedule contains conflicting office hours.
+	RollbackToVersion(ctx context.Context, version int64) (*ScheduleVersion, error)
+
+	// SetOverride creates or replaces the override of o.Location.
+	SetOverride(ctx context.Context, o *Override) error
+
+	// GetOverride returns the most recent override of location or nil.
+	// Overrides that ended are kept for some time so the returned override
+	// might not be active anymore (see Override.ActiveAt).
+	GetOverride(ctx context.Context, location string) (*Override, error)
+
+	// RevokeOverride ends the active override of location at t or returns
+	// ErrNotFound if there is none.
+	RevokeOverride(ctx context.Context, location string, t time.Time) error
+
+	// CreateDraft creates a new draft as a copy of the live schedule.
+	CreateDraft(ctx context.Context, description string) (*Draft, error)
+
+	// GetDraft returns a draft or ErrDraftNotFound.
+	GetDraft(ctx context.Context, id string) (*Draft, error)
+
+	// ListDrafts returns all drafts without their office hours.
+	ListDrafts(ctx context.Context) ([]Draft, error)
+
+	// UpdateDraft replaces the office hours and the publishing schedule of
+	// an unpublished draft. It returns ErrRevisionMismatch if the draft has
+	// been updated since it was read.
+	UpdateDraft(ctx context.Context, draft *Draft) error
+
+	// DeleteDraft deletes a draft or returns ErrDraftNotFound.
+	DeleteDraft(ctx context.Context, id string) error
+
+	// PublishDraft replaces the live schedule with the office hours of a
+	// draft and returns the new version. It returns ErrDraftOutdated if the
+	// live schedule has been changed since the draft was created and a
+	// *ValidationError if the draft contains conflicting office hours.
+	PublishDraft(ctx context.Context, id string) (*ScheduleVersion, error)
+
+	// DueDrafts returns all unpublished drafts that are scheduled to be
+	// published at or before t.
+	DueDrafts(ctx context.Context, t time.Time) ([]Draft, error)
+
+	// ExportSchedule returns the live schedule as a schedule document.
+	ExportSchedule(ctx context.Context) (*ScheduleDocument, error)
+
+	// ImportSchedule replaces the live schedule with the office hours of
+	// doc and returns the applied changes.
+	ImportSchedule(ctx context.Context, doc *ScheduleDocument, dryRun bool) (ScheduleDiff, *ScheduleVersion, error)
+
+	// EnqueueEvent stores msg in the outbox using the idempotency key.
+	EnqueueEvent(ctx context.Context, key string, msg proto.Message) error
+
+	// PendingEvents returns all events that have not been delivered yet in
+	// the order they have been enqueued.
+	PendingEvents(ctx context.Context) ([]OutboxEntry, error)
+
+	// MarkEventDelivered marks an event as delivered.
+	MarkEventDelivered(ctx context.Context, key string) error
+
+	// MarkEventFailed records a failed delivery attempt of an event.
+	MarkEventFailed(ctx context.Context, key string, nextAttempt time.Time, deliveryErr error) error
+
+	// MarkEventDead records the final failed delivery attempt of an event
+	// which is not delivered anymore.
+	MarkEventDead(ctx context.Context, key string, deliveryErr error) error
+
+	// AcquireLease tries to acquire or renew a lease for holder.
+	AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+
+	// ReleaseLease releases a lease if it is held by holder.
+	ReleaseLease(ctx context.Context, name string, holder string) error
+}
+
+var (
+	_ OfficeHourStore = (*Repo)(nil)
+	_ OfficeHourStore = (*MemoryStore)(nil)
+	_ OfficeHourStore = (*FileStore)(nil)
+)
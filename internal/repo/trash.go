@@ -0,0 +1,125 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListDeletedOfficeHours returns all office hours in the trash, most recently
+// deleted ones first. If location is set, only office hours of that location
+// are returned.
+func (r *Repo) ListDeletedOfficeHours(ctx context.Context, location string) ([]OfficeHourModel, error) {
+	filter := bson.M{}
+	if location != "" {
+		filter = locationFilter(location)
+	}
+
+	filter["deletedAt"] = bson.M{"$exists": true}
+
+	res, err := r.col.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	var models []OfficeHourModel
+	if err := res.All(ctx, &models); err != nil {
+		return nil, fmt.Errorf("failed to decode office-hour documents: %w", err)
+	}
+
+	return models, nil
+}
+
+// RestoreOfficeHour moves the office hour identified by name out of the
+// trash. ErrNotFound is returned if there is no such office hour in the
+// trash and a *ValidationError if it conflicts with the live schedule. The
+// mutation is recorded in the audit log using the actor from ctx in the same
+// transaction.
+func (r *Repo) RestoreOfficeHour(ctx context.Context, name string) (*OfficeHourModel, error) {
+	oid, err := primitive.ObjectIDFromHex(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid office-hour name: %w", err)
+	}
+
+	var current OfficeHourModel
+
+	err = r.withTransaction(ctx, func(ctx context.Context) error {
+		var previous OfficeHourModel
+		if err := r.col.FindOneAndUpdate(ctx, bson.M{
+			"_id":       oid,
+			"deletedAt": bson.M{"$exists": true},
+		}, bson.M{
+			"$unset": bson.M{
+				"deletedAt": "",
+				"deletedBy": "",
+			},
+			"$inc": bson.M{
+				"revision": 1,
+			},
+		}).Decode(&previous); err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return ErrNotFound
+			}
+
+			return err
+		}
+
+		current = previous.restored()
+
+		// the live schedule might have been changed since the office hour
+		// has been deleted.
+		live, err := r.ListOfficeHours(ctx, current.Location)
+		if err != nil {
+			return err
+		}
+
+		if err := current.ValidateWith(live); err != nil {
+			return err
+		}
+
+		if err := r.recordAudit(ctx, AuditActionRestore, name, &previous, &current); err != nil {
+			return err
+		}
+
+		_, err = r.snapshot(ctx, "restore "+name)
+
+		return err
+	})
+
+	r.invalidateCache()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &current, nil
+}
+
+// PurgeDeletedOfficeHours permanently deletes all office hours that have
+// been moved to the trash before t and returns the number of deleted office
+// hours.
+func (r *Repo) PurgeDeletedOfficeHours(ctx context.Context, t time.Time) (int, error) {
+	res, err := r.col.DeleteMany(ctx, bson.M{
+		"deletedAt": bson.M{"$lt": t},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted office hours: %w", err)
+	}
+
+	return int(res.DeletedCount), nil
+}
+
+// restored returns a copy of m that has been restored from the trash.
+func (m OfficeHourModel) restored() OfficeHourModel {
+	m.DeletedAt = nil
+	m.DeletedBy = nil
+	m.Revision++
+
+	return m
+}
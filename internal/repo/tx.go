@@ -0,0 +1,100 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// detectTransactions checks whether the database supports multi-document
+// transactions which are only available on replica sets and sharded
+// clusters. On a standalone server, an error is returned unless
+// allowNonAtomic is set in which case changes are written without a
+// transaction (see withTransaction).
+func (r *Repo) detectTransactions(ctx context.Context, allowNonAtomic bool) error {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	if err := r.client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return fmt.Errorf("failed to query mongodb topology: %w", err)
+	}
+
+	r.transactions = hello.SetName != "" || hello.Msg == "isdbgrid"
+
+	if !r.transactions {
+		if !allowNonAtomic {
+			return fmt.Errorf("mongodb does not support transactions, use a replica set or explicitly allow non-atomic writes")
+		}
+
+		slog.Warn("mongodb does not support transactions, changes and their audit entries and schedule versions are not written atomically")
+	}
+
+	return nil
+}
+
+// withTransaction runs fn in a transaction and retries it on transient
+// errors. If ctx already belongs to a transaction, fn joins it. If the
+// database does not support transactions and non-atomic writes have been
+// allowed, fn is called without one.
+func (r *Repo) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !r.transactions || mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := r.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	})
+
+	return err
+}
+
+// nextVersion returns the next schedule version number. Versions are
+// numbered using a counter document so concurrent transactions conflict
+// instead of creating the same version.
+func (r *Repo) nextVersion(ctx context.Context) (int64, error) {
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+
+	if err := r.counters.FindOneAndUpdate(ctx, bson.M{"_id": "versions"}, bson.M{
+		"$inc": bson.M{"value": 1},
+	}, options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)).Decode(&counter); err != nil {
+		return 0, fmt.Errorf("failed to allocate schedule version: %w", err)
+	}
+
+	return counter.Value, nil
+}
+
+// setupVersionCounter initializes the version counter with the last
+// schedule version created before the counter was introduced.
+func (r *Repo) setupVersionCounter(ctx context.Context) error {
+	var last ScheduleVersion
+
+	err := r.versions.FindOne(ctx, bson.M{}, options.FindOne().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"_id": 1})).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return fmt.Errorf("failed to determine last schedule version: %w", err)
+	}
+
+	if _, err := r.counters.UpdateOne(ctx, bson.M{"_id": "versions"}, bson.M{
+		"$max": bson.M{"value": last.Version},
+	}, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to initialize schedule version counter: %w", err)
+	}
+
+	return nil
+}
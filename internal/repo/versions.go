@@ -0,0 +1,372 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrVersionNotFound is returned if a schedule version does not exist.
+var ErrVersionNotFound = errors.New("schedule version not found")
+
+// ScheduleVersion is a snapshot of all office hours taken after a change.
+type ScheduleVersion struct {
+	// Version is the number of the version. Versions are numbered starting
+	// from 1.
+	Version int64 `bson:"_id" json:"version"`
+
+	// Description describes the change that created the version.
+	Description string `bson:"description" json:"description"`
+
+	Actor     Actor     `bson:"actor" json:"actor"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+
+	// OfficeHours holds all office hours of the version. It is not set when
+	// listing versions.
+	OfficeHours []OfficeHourModel `bson:"officeHours,omitempty" json:"officeHours,omitempty"`
+}
+
+// ChangedOfficeHour describes an office hour that exists in both versions of
+// a diff but differs.
+type ChangedOfficeHour struct {
+	Previous OfficeHourModel `json:"previous"`
+	Current  OfficeHourModel `json:"current"`
+}
+
+// ScheduleDiff describes the differences between two sets of office hours.
+type ScheduleDiff struct {
+	Added   []OfficeHourModel   `json:"added"`
+	Removed []OfficeHourModel   `json:"removed"`
+	Changed []ChangedOfficeHour `json:"changed"`
+}
+
+// IsEmpty reports whether d does not contain any differences.
+func (d ScheduleDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSchedules returns the changes required to turn from into to. Office
+// hours are matched by their ID.
+func DiffSchedules(from, to []OfficeHourModel) ScheduleDiff {
+	var diff ScheduleDiff
+
+	previous := make(map[string]OfficeHourModel, len(from))
+	for _, m := range from {
+		previous[m.ID.Hex()] = m
+	}
+
+	for _, m := range to {
+		p, ok := previous[m.ID.Hex()]
+		delete(previous, m.ID.Hex())
+
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, m)
+
+		case !sameOfficeHour(p, m):
+			diff.Changed = append(diff.Changed, ChangedOfficeHour{
+				Previous: p,
+				Current:  m,
+			})
+		}
+	}
+
+	for _, m := range from {
+		if _, ok := previous[m.ID.Hex()]; ok {
+			diff.Removed = append(diff.Removed, m)
+		}
+	}
+
+	return diff
+}
+
+// sameOfficeHour reports whether a and b are equal except for their revision.
+func sameOfficeHour(a, b OfficeHourModel) bool {
+	a.Revision, b.Revision = 0, 0
+
+	return reflect.DeepEqual(a, b)
+}
+
+// withRevisions returns a copy of diff with the revisions of all added and
+// changed office hours set to their next revision. stored holds the
+// revisions of added office hours that still exist in the trash so their
+// revisions continue instead of starting over.
+func (diff ScheduleDiff) withRevisions(stored map[primitive.ObjectID]int64) ScheduleDiff {
+	result := ScheduleDiff{
+		Removed: diff.Removed,
+		Added:   make([]OfficeHourModel, len(diff.Added)),
+		Changed: make([]ChangedOfficeHour, len(diff.Changed)),
+	}
+
+	for idx, m := range diff.Added {
+		m.Revision = stored[m.ID] + 1
+		result.Added[idx] = m
+	}
+
+	for idx, c := range diff.Changed {
+		c.Current.Revision = c.Previous.Revision + 1
+		result.Changed[idx] = c
+	}
+
+	return result
+}
+
+// ListVersions returns all schedule versions without their office hours,
+// most recent versions first.
+func (r *Repo) ListVersions(ctx context.Context) ([]ScheduleVersion, error) {
+	res, err := r.versions.Find(ctx, bson.M{}, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"officeHours": 0}))
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []ScheduleVersion
+	if err := res.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("failed to decode schedule versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetVersion returns the schedule version identified by version.
+func (r *Repo) GetVersion(ctx context.Context, version int64) (*ScheduleVersion, error) {
+	var v ScheduleVersion
+	if err := r.versions.FindOne(ctx, bson.M{"_id": version}).Decode(&v); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrVersionNotFound
+		}
+
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// RollbackToVersion restores all office hours to the state of version. The
+// restored schedule is stored as a new version which is returned. All
+// changes are applied in a single transaction. A *ValidationError is
+// returned if the restored schedule contains conflicting office hours.
+func (r *Repo) RollbackToVersion(ctx context.Context, version int64) (*ScheduleVersion, error) {
+	target, err := r.GetVersion(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ScheduleVersion
+
+	err = r.withTransaction(ctx, func(ctx context.Context) error {
+		current, err := r.ListOfficeHours(ctx, "")
+		if err != nil {
+			return err
+		}
+
+		// the rollback replaces the whole schedule so the resulting
+		// schedule is the restored version.
+		if err := Schedule(target.OfficeHours).Validate(); err != nil {
+			return err
+		}
+
+		diff := DiffSchedules(current, target.OfficeHours)
+		if err := r.applyDiff(ctx, diff); err != nil {
+			return err
+		}
+
+		result, err = r.snapshot(ctx, fmt.Sprintf("rollback to version %d", version))
+
+		return err
+	})
+
+	r.invalidateCache()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// applyDiff applies diff to the office-hours collection using a single bulk
+// write and records all changes in the audit log. It must be called in a
+// transaction (see withTransaction) so the changes and their audit entries
+// are applied atomically.
+//
+// Removed and changed office hours are only written if their revision still
+// matches the revision of the diff. Otherwise, ErrRevisionMismatch is
+// returned and the transaction is aborted.
+//
+// Callers must invalidate the cache once the transaction has finished.
+// Invalidating it earlier could cause the cache to be reloaded with the
+// state from before the transaction is committed.
+func (r *Repo) applyDiff(ctx context.Context, diff ScheduleDiff) error {
+	if diff.IsEmpty() {
+		return nil
+	}
+
+	stored, err := r.storedRevisions(ctx, diff.Added)
+	if err != nil {
+		return err
+	}
+
+	diff = diff.withRevisions(stored)
+
+	var (
+		writes   []mongo.WriteModel
+		expected int64
+	)
+
+	now := time.Now()
+	actor := ActorFrom(ctx)
+
+	// removed office hours are moved to the trash
+	for _, m := range diff.Removed {
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{
+			"_id":       m.ID,
+			"revision":  m.Revision,
+			"deletedAt": notDeleted,
+		}).SetUpdate(bson.M{
+			"$set": bson.M{
+				"deletedAt": now,
+				"deletedBy": actor,
+			},
+			"$inc": bson.M{
+				"revision": 1,
+			},
+		}))
+		expected++
+	}
+
+	for _, m := range diff.Added {
+		// office hours restored from the trash replace the trashed document
+		if revision, ok := stored[m.ID]; ok {
+			writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{
+				"_id":      m.ID,
+				"revision": revision,
+			}).SetReplacement(m))
+			expected++
+
+			continue
+		}
+
+		writes = append(writes, mongo.NewInsertOneModel().SetDocument(m))
+	}
+
+	for _, c := range diff.Changed {
+		writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{
+			"_id":       c.Current.ID,
+			"revision":  c.Previous.Revision,
+			"deletedAt": notDeleted,
+		}).SetReplacement(c.Current))
+		expected++
+	}
+
+	res, err := r.col.BulkWrite(ctx, writes)
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		// an added office hour has been created concurrently
+		return ErrRevisionMismatch
+
+	case err != nil:
+		return fmt.Errorf("failed to apply schedule changes: %w", err)
+
+	case res.MatchedCount != expected:
+		return ErrRevisionMismatch
+	}
+
+	for _, m := range diff.Removed {
+		if err := r.recordAudit(ctx, AuditActionDelete, m.ID.Hex(), &m, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range diff.Added {
+		if err := r.recordAudit(ctx, AuditActionUpsert, m.ID.Hex(), nil, &m); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range diff.Changed {
+		if err := r.recordAudit(ctx, AuditActionUpsert, c.Current.ID.Hex(), &c.Previous, &c.Current); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// storedRevisions returns the revisions of all office hours in models that
+// are already stored, for example because they are in the trash.
+func (r *Repo) storedRevisions(ctx context.Context, models []OfficeHourModel) (map[primitive.ObjectID]int64, error) {
+	if len(models) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(models))
+	for idx, m := range models {
+		ids[idx] = m.ID
+	}
+
+	res, err := r.col.Find(ctx, bson.M{
+		"_id": bson.M{"$in": ids},
+	}, options.Find().SetProjection(bson.M{"revision": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored revisions: %w", err)
+	}
+
+	var docs []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		Revision int64              `bson:"revision"`
+	}
+	if err := res.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode stored revisions: %w", err)
+	}
+
+	stored := make(map[primitive.ObjectID]int64, len(docs))
+	for _, d := range docs {
+		stored[d.ID] = d.Revision
+	}
+
+	return stored, nil
+}
+
+// snapshot stores all current office hours as a new schedule version. It
+// should be called in the transaction of the change it records.
+func (r *Repo) snapshot(ctx context.Context, description string) (*ScheduleVersion, error) {
+	hours, err := r.ListOfficeHours(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	v := &ScheduleVersion{
+		Description: description,
+		Actor:       ActorFrom(ctx),
+		CreatedAt:   time.Now(),
+		OfficeHours: Schedule(hours).Sorted(),
+	}
+
+	v.Version, err = r.nextVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.versions.InsertOne(ctx, v); err != nil {
+		return nil, fmt.Errorf("failed to store schedule version: %w", err)
+	}
+
+	if r.versionRetention > 0 {
+		if _, err := r.versions.DeleteMany(ctx, bson.M{
+			"_id": bson.M{"$lte": v.Version - int64(r.versionRetention)},
+		}); err != nil {
+			return nil, fmt.Errorf("failed to remove old schedule versions: %w", err)
+		}
+	}
+
+	return v, nil
+}
@@ -0,0 +1,166 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testModel(id byte, weekday time.Weekday, revision int64) OfficeHourModel {
+	return OfficeHourModel{
+		ID:        primitive.ObjectID{11: id},
+		DayOfWeek: weekday,
+		Revision:  revision,
+		TimeRanges: []DayTimeRange{
+			{Start: DayTime{Hours: 8}, End: DayTime{Hours: 12}},
+		},
+	}
+}
+
+func TestDiffSchedules(t *testing.T) {
+	var (
+		monday        = testModel(1, time.Monday, 1)
+		mondayRev2    = testModel(1, time.Monday, 2)
+		mondayChanged = testModel(1, time.Tuesday, 2)
+		friday        = testModel(2, time.Friday, 1)
+		fridayCopy    = testModel(3, time.Friday, 1)
+	)
+
+	cases := []struct {
+		name     string
+		from, to []OfficeHourModel
+		added    []OfficeHourModel
+		removed  []OfficeHourModel
+		changed  []OfficeHourModel
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "added",
+			to:    []OfficeHourModel{monday},
+			added: []OfficeHourModel{monday},
+		},
+		{
+			name:    "removed",
+			from:    []OfficeHourModel{monday, friday},
+			to:      []OfficeHourModel{friday},
+			removed: []OfficeHourModel{monday},
+		},
+		{
+			name:    "changed",
+			from:    []OfficeHourModel{monday, friday},
+			to:      []OfficeHourModel{friday, mondayChanged},
+			changed: []OfficeHourModel{mondayChanged},
+		},
+		{
+			name: "revisions are ignored",
+			from: []OfficeHourModel{monday},
+			to:   []OfficeHourModel{mondayRev2},
+		},
+		{
+			name:    "office hours are matched by id",
+			from:    []OfficeHourModel{friday},
+			to:      []OfficeHourModel{fridayCopy},
+			added:   []OfficeHourModel{fridayCopy},
+			removed: []OfficeHourModel{friday},
+		},
+	}
+
+	ids := func(models []OfficeHourModel) []string {
+		result := make([]string, len(models))
+		for idx, m := range models {
+			result[idx] = m.ID.Hex()
+		}
+
+		return result
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			diff := DiffSchedules(c.from, c.to)
+
+			if diff.IsEmpty() != (len(c.added)+len(c.removed)+len(c.changed) == 0) {
+				t.Errorf("unexpected IsEmpty() = %v", diff.IsEmpty())
+			}
+
+			if got, expected := ids(diff.Added), ids(c.added); !slices.Equal(got, expected) {
+				t.Errorf("expected added %v but got %v", expected, got)
+			}
+
+			if got, expected := ids(diff.Removed), ids(c.removed); !slices.Equal(got, expected) {
+				t.Errorf("expected removed %v but got %v", expected, got)
+			}
+
+			if len(diff.Changed) != len(c.changed) {
+				t.Fatalf("expected %d changed office hours but got %d", len(c.changed), len(diff.Changed))
+			}
+
+			for idx, change := range diff.Changed {
+				if change.Current.DayOfWeek != c.changed[idx].DayOfWeek || change.Previous.ID != change.Current.ID {
+					t.Errorf("unexpected change %d: %+v", idx, change)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreVersionNumbering(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	monday := testModel(1, time.Monday, 0)
+	if _, err := store.UpsertOfficeHours(ctx, &monday); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	friday := testModel(2, time.Friday, 0)
+	if _, err := store.UpsertOfficeHours(ctx, &friday); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if err := store.DeleteOfficeHour(ctx, monday.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	v, err := store.RollbackToVersion(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to roll back: %s", err)
+	}
+
+	// the rollback is recorded as a new version
+	if v.Version != 4 {
+		t.Errorf("expected the rollback to create version 4 but got %d", v.Version)
+	}
+
+	if len(v.OfficeHours) != 1 || v.OfficeHours[0].ID != monday.ID {
+		t.Fatalf("expected version 4 to only contain the monday office hour, got %d office hours", len(v.OfficeHours))
+	}
+
+	// restoring an office hour from the trash continues its revision
+	if v.OfficeHours[0].Revision != 3 {
+		t.Errorf("expected the restored office hour to have revision 3 but got %d", v.OfficeHours[0].Revision)
+	}
+
+	versions, err := store.ListVersions(ctx)
+	if err != nil {
+		t.Fatalf("failed to list versions: %s", err)
+	}
+
+	for idx, v := range versions {
+		if expected := int64(len(versions) - idx); v.Version != expected {
+			t.Errorf("expected version %d at index %d but got %d", expected, idx, v.Version)
+		}
+
+		if v.OfficeHours != nil {
+			t.Errorf("expected listed versions to not contain office hours")
+		}
+	}
+
+	if _, err := store.GetVersion(ctx, 5); err != ErrVersionNotFound {
+		t.Errorf("expected ErrVersionNotFound but got %v", err)
+	}
+}
@@ -3,31 +3,170 @@ package resolver
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/bufbuild/connect-go"
-	calendarv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/calendar/v1"
 	v1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/office_hours/v1"
-	"github.com/tierklinik-dobersberg/apis/pkg/discovery"
-	"github.com/tierklinik-dobersberg/apis/pkg/discovery/wellknown"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
 )
 
+// DayOfficeHours holds the office hours that are valid at a given day.
+type DayOfficeHours struct {
+	// Date is the start of the day.
+	Date time.Time
+
+	// OfficeHours holds all office hours that are valid at Date.
+	OfficeHours []repo.OfficeHourModel
+}
+
+// OpenState describes whether a location is open at a given time and when
+// the next state changes are expected.
+type OpenState struct {
+	// Open is true if the location is open.
+	Open bool
+
+	// NextOpen is the next time the location opens. It is zero if the
+	// location does not open within the searched horizon.
+	NextOpen time.Time
+
+	// NextClose is the next time the location closes. It is zero if the
+	// location does not close within the searched horizon.
+	NextClose time.Time
+
+	// Mode is the opening mode that applies if the location is open. If
+	// the location is closed, Mode is repo.ModePhoneOnly if the location
+	// can be reached by phone and empty otherwise.
+	Mode repo.OpeningMode
+
+	// Since is the time at which the current state began. It is zero if
+	// it lies outside of the searched days.
+	Since time.Time
+
+	// NextTransition is the earliest time at which either the open state or
+	// the opening mode might change. It is zero if there are no more
+	// transitions within the searched horizon.
+	NextTransition time.Time
+
+	// OfficeHour is the office hour that applies if the location is open.
+	OfficeHour *repo.OfficeHourModel
+
+	// Override is set if the state is forced by a manual override.
+	Override *repo.Override
+}
+
+// NextChange returns the time of the next state change or the zero time if
+// the state does not change within the searched horizon.
+func (s *OpenState) NextChange() time.Time {
+	if s.Open {
+		return s.NextClose
+	}
+
+	return s.NextOpen
+}
+
+// HolidayProvider is used by the Resolver to determine public holidays.
+type HolidayProvider interface {
+	// PublicHolidays returns the dates (YYYY-MM-DD) of all public holidays
+	// in the given month. If the authoritative source is unavailable, a
+	// provider may return a best-effort result together with an error
+	// wrapping ErrDegraded.
+	PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error)
+}
+
+// ErrDegraded is returned by a HolidayProvider together with a best-effort
+// result, for example the last known or a static list of holidays. The
+// result is used but only cached for degradedCacheTTL so the authoritative
+// source is queried again soon.
+var ErrDegraded = errors.New("public holidays are degraded")
+
+// degradedCacheTTL is how long degraded holidays are cached.
+const degradedCacheTTL = time.Minute
+
+type monthKey struct {
+	year  int
+	month time.Month
+}
+
+type cachedHolidays struct {
+	dates   []string
+	expires time.Time
+}
+
+// OfficeHourSource provides the office hours considered by the Resolver.
+type OfficeHourSource interface {
+	// FindByTime returns all office hours of location that might apply at
+	// the day of t.
+	FindByTime(ctx context.Context, t time.Time, location string) ([]repo.OfficeHourModel, error)
+
+	// FindBetween returns all office hours of location that might apply at
+	// any day between from and to (both inclusive).
+	FindBetween(ctx context.Context, from, to time.Time, location string) ([]repo.OfficeHourModel, error)
+}
+
+type holidayCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	lock    sync.Mutex
+	entries map[monthKey]cachedHolidays
+}
+
 type Resolver struct {
-	repo    *repo.Repo
-	catalog discovery.Discoverer
+	repo     repo.OfficeHourStore
+	source   OfficeHourSource
+	holidays HolidayProvider
+	tz       *time.Location
+	cache    *holidayCache
 }
 
-func NewResolver(repo *repo.Repo, catalog discovery.Discoverer) *Resolver {
+// NewResolver returns a new resolver that interprets office hours in the
+// timezone tz. Public holidays returned by holidays are cached for cacheTTL.
+// A cacheTTL of zero disables caching.
+func NewResolver(repo repo.OfficeHourStore, holidays HolidayProvider, cacheTTL time.Duration, tz *time.Location) *Resolver {
 	return &Resolver{
-		repo:    repo,
-		catalog: catalog,
+		repo:     repo,
+		source:   repo,
+		holidays: holidays,
+		tz:       tz,
+		cache: &holidayCache{
+			ttl:     cacheTTL,
+			now:     time.Now,
+			entries: make(map[monthKey]cachedHolidays),
+		},
 	}
 }
 
-func (r *Resolver) ResolveOfficeHours(ctx context.Context, t time.Time) ([]*v1.OfficeHour, error) {
-	hours, err := r.repo.FindByTime(ctx, t)
+// WithSource returns a resolver that resolves the office hours provided by
+// source instead of the live schedule. The holiday cache is shared with r.
+func (r *Resolver) WithSource(source OfficeHourSource) *Resolver {
+	return &Resolver{
+		repo:     r.repo,
+		source:   source,
+		holidays: r.holidays,
+		tz:       r.tz,
+		cache:    r.cache,
+	}
+}
+
+// InvalidateHolidayCache drops all cached public holidays.
+func (r *Resolver) InvalidateHolidayCache() {
+	r.cache.lock.Lock()
+	defer r.cache.lock.Unlock()
+
+	clear(r.cache.entries)
+
+	slog.Debug("holiday cache invalidated")
+}
+
+// ResolveOfficeHours returns all office hours of location that are valid at
+// the day of t. An empty location resolves the office hours of the default
+// location.
+func (r *Resolver) ResolveOfficeHours(ctx context.Context, t time.Time, location string) ([]repo.OfficeHourModel, error) {
+	t = t.In(r.tz)
+
+	hours, err := r.source.FindByTime(ctx, t, location)
 	if err != nil {
 		// If it's a NotFound error there are not office hours for the given date,
 		// thus, just return a normal response.
@@ -45,46 +184,336 @@ func (r *Resolver) ResolveOfficeHours(ctx context.Context, t time.Time) ([]*v1.O
 		return nil, err
 	}
 
-	// we actually expect to only find one
-	validHours := make([]*v1.OfficeHour, 0, 1)
-	for _, h := range hours {
-		switch {
-		case isHoliday && h.HolidayCondition != v1.HolidayCondition_HOLIDAY_CONDITION_UNSPECIFIED:
-			validHours = append(validHours, h)
+	return selectOfficeHours(hours, isHoliday), nil
+}
 
-		case !isHoliday && h.HolidayCondition != v1.HolidayCondition_EXCLUSIVE:
-			validHours = append(validHours, h)
+// ResolveOfficeHoursBetween resolves the office hours of location for each day
+// between from and to (both inclusive). In contrast to calling ResolveOfficeHours
+// for each day, office hours and public holidays are only fetched once.
+func (r *Resolver) ResolveOfficeHoursBetween(ctx context.Context, from, to time.Time, location string) ([]DayOfficeHours, error) {
+	from = startOfDay(from.In(r.tz))
+	to = startOfDay(to.In(r.tz))
+
+	hours, err := r.source.FindBetween(ctx, from, to, location)
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		return nil, err
+	}
+
+	// fetch public holidays once per month
+	holidays := make(map[string]bool)
+	for m := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location()); !m.After(to); m = m.AddDate(0, 1, 0) {
+		if err := r.fetchHolidays(ctx, m.Year(), m.Month(), holidays); err != nil {
+			return nil, err
 		}
 	}
 
-	return validHours, nil
+	var result []DayOfficeHours
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		var matching []repo.OfficeHourModel
+		for _, h := range hours {
+			if h.AppliesAt(d) {
+				matching = append(matching, h)
+			}
+		}
+
+		result = append(result, DayOfficeHours{
+			Date:        d,
+			OfficeHours: selectOfficeHours(matching, holidays[d.Format("2006-01-02")]),
+		})
+	}
+
+	return result, nil
 }
 
-func (r *Resolver) isHoliday(ctx context.Context, t time.Time) (bool, error) {
-	holidayClient, err := wellknown.HolidayService.Create(ctx, r.catalog)
+// ResolveOpenState returns the open state of location at t and searches the
+// office hours of the following days, up to horizon, for the next opening and
+// closing times. The office hours of the previous day are considered as well
+// since they might span midnight.
+func (r *Resolver) ResolveOpenState(ctx context.Context, t time.Time, location string, horizon time.Duration) (*OpenState, error) {
+	t = t.In(r.tz)
+
+	days, err := r.ResolveOfficeHoursBetween(ctx, t.AddDate(0, 0, -1), t.Add(horizon), location)
 	if err != nil {
-		return false, fmt.Errorf("failed to get holiday client using service catalog: %w", err)
+		return nil, err
 	}
 
-	holidayResponse, err := holidayClient.GetHoliday(ctx, connect.NewRequest(&calendarv1.GetHolidayRequest{
-		Year:  uint64(t.Year()),
-		Month: uint64(t.Month()),
-	}))
-
+	override, err := r.repo.GetOverride(ctx, location)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch holidays: %w", err)
+		return nil, err
+	}
+
+	if override != nil && override.ActiveAt(t) {
+		return applyOverride(days, t, override), nil
+	}
+
+	state := computeOpenState(days, t)
+
+	// The regular state only began once the override ended. This ensures
+	// that returning to a state after an override is reported as a new
+	// transition.
+	if override != nil && override.Until != nil && !override.Until.After(t) && override.Until.After(state.Since) {
+		state.Since = *override.Until
+	}
+
+	return state, nil
+}
+
+// applyOverride returns the open state at t forced by the active override o.
+func applyOverride(days []DayOfficeHours, t time.Time, o *repo.Override) *OpenState {
+	state := &OpenState{
+		Open:     o.Open,
+		Mode:     o.OpeningMode(),
+		Since:    o.CreatedAt,
+		Override: o,
+	}
+
+	// overrides without an expiry never change
+	if o.Until == nil {
+		return state
+	}
+
+	state.NextTransition = *o.Until
+
+	// the regular office hours apply again once the override expired
+	after := computeOpenState(days, *o.Until)
+
+	switch {
+	case o.Open && after.Open:
+		state.NextClose = after.NextClose
+	case o.Open:
+		state.NextClose = *o.Until
+	case after.Open:
+		state.NextOpen = *o.Until
+	default:
+		state.NextOpen = after.NextOpen
+	}
+
+	return state
+}
+
+// modePriority defines which opening mode is reported if time ranges with
+// different modes overlap. Lower values take precedence. Phone-only time
+// ranges do not open a location and are thus not listed.
+var modePriority = map[repo.OpeningMode]int{
+	repo.ModeConsultation:     0,
+	repo.ModeAppointmentsOnly: 1,
+	repo.ModeEmergencyOnly:    2,
+}
+
+// computeOpenState returns the open state at t based on the resolved
+// office hours in days.
+func computeOpenState(days []DayOfficeHours, t time.Time) *OpenState {
+	state := new(OpenState)
+
+	// phoneOnly is set if a phone-only time range applies at t.
+	var phoneOnly bool
+
+	// collect all open ranges and sort them by start time
+	var ranges [][2]time.Time
+	for _, day := range days {
+		for hIdx, h := range day.OfficeHours {
+			for _, tr := range h.TimeRanges {
+				start, end := tr.At(day.Date)
+				mode := tr.OpeningMode()
+
+				// changes of the phone-only state are transitions as well
+				for _, b := range []time.Time{start, end} {
+					if b.After(t) && (b.Before(state.NextTransition) || state.NextTransition.IsZero()) {
+						state.NextTransition = b
+					}
+				}
+
+				if mode == repo.ModePhoneOnly {
+					if !start.After(t) && end.After(t) {
+						phoneOnly = true
+					}
+
+					continue
+				}
+
+				if !start.After(t) && end.After(t) {
+					if state.OfficeHour == nil || modePriority[mode] < modePriority[state.Mode] {
+						state.OfficeHour = &day.OfficeHours[hIdx]
+						state.Mode = mode
+						state.Since = start
+					}
+				}
+
+				ranges = append(ranges, [2]time.Time{start, end})
+			}
+		}
+	}
+
+	if state.OfficeHour == nil && phoneOnly {
+		state.Mode = repo.ModePhoneOnly
+	}
+
+	slices.SortFunc(ranges, func(a, b [2]time.Time) int {
+		return a[0].Compare(b[0])
+	})
+
+	// merge overlapping ranges so transitions between them are not
+	// reported as state changes.
+	var merged [][2]time.Time
+	for _, tr := range ranges {
+		if last := len(merged) - 1; last >= 0 && !tr[0].After(merged[last][1]) {
+			if tr[1].After(merged[last][1]) {
+				merged[last][1] = tr[1]
+			}
+
+			continue
+		}
+
+		merged = append(merged, tr)
 	}
 
-	dateKey := t.Format("2006-01-02")
-	for _, holiday := range holidayResponse.Msg.Holidays {
-		if holiday.Date == dateKey {
-			if holiday.Type == calendarv1.HolidayType_PUBLIC {
-				return true, nil
+	for _, tr := range merged {
+		switch {
+		case !tr[1].After(t):
+			// already over
+			if state.OfficeHour == nil {
+				state.Since = tr[1]
+			}
+
+			continue
+
+		case !tr[0].After(t):
+			// currently open
+			state.Open = true
+			state.NextClose = tr[1]
+
+		default:
+			state.NextOpen = tr[0]
+			if state.NextClose.IsZero() {
+				state.NextClose = tr[1]
 			}
 
-			break
+			return state
 		}
 	}
 
-	return false, nil
+	return state
+}
+
+func (r *Resolver) isHoliday(ctx context.Context, t time.Time) (bool, error) {
+	holidays := make(map[string]bool)
+	if err := r.fetchHolidays(ctx, t.Year(), t.Month(), holidays); err != nil {
+		return false, err
+	}
+
+	return holidays[t.Format("2006-01-02")], nil
+}
+
+// fetchHolidays adds the dates (in the format of YYYY-MM-DD) of all public
+// holidays in the given month to holidays.
+func (r *Resolver) fetchHolidays(ctx context.Context, year int, month time.Month, holidays map[string]bool) error {
+	key := monthKey{year, month}
+
+	r.cache.lock.Lock()
+	cached, ok := r.cache.entries[key]
+	r.cache.lock.Unlock()
+
+	dates := cached.dates
+	if ok && r.cache.now().Before(cached.expires) {
+		slog.Debug("holiday cache hit", "year", year, "month", month)
+	} else {
+		slog.Debug("holiday cache miss", "year", year, "month", month)
+
+		var err error
+		dates, err = r.holidays.PublicHolidays(ctx, year, month)
+
+		ttl := r.cache.ttl
+		switch {
+		case errors.Is(err, ErrDegraded):
+			slog.Debug("using degraded holidays", "year", year, "month", month, "error", err)
+
+			ttl = min(ttl, degradedCacheTTL)
+
+		case err != nil:
+			return err
+		}
+
+		if ttl > 0 {
+			r.cache.lock.Lock()
+			r.cache.entries[key] = cachedHolidays{
+				dates:   dates,
+				expires: r.cache.now().Add(ttl),
+			}
+			r.cache.lock.Unlock()
+		}
+	}
+
+	for _, d := range dates {
+		holidays[d] = true
+	}
+
+	return nil
+}
+
+// selectOfficeHours returns the office hours that apply at a single day
+// from the set of matching office hours. The following rules are applied in
+// order:
+//
+//  1. Office hours are filtered by their holiday condition: on public holidays
+//     only INCLUDE and EXCLUSIVE office hours and closures are considered
+//     while EXCLUSIVE office hours are ignored on all other days.
+//  2. Only the most specific office hours are kept. A date with a year takes
+//     precedence over a recurring date (without year) which takes precedence
+//     over a date range which takes precedence over a day-of-week.
+//  3. On public holidays, EXCLUSIVE office hours take precedence over INCLUDE
+//     office hours of the same specificity.
+//  4. Remaining office hours are ordered by name.
+//
+// Date specific office hours and date ranges without time ranges are
+// closures and thus override less specific office hours, closing the whole
+// day.
+func selectOfficeHours(hours []repo.OfficeHourModel, isHoliday bool) []repo.OfficeHourModel {
+	candidates := make([]repo.OfficeHourModel, 0, len(hours))
+	for _, h := range hours {
+		switch {
+		case isHoliday && h.HolidayCondition != v1.HolidayCondition_HOLIDAY_CONDITION_UNSPECIFIED:
+			candidates = append(candidates, h)
+
+		// closures apply regardless of their holiday condition so the
+		// location stays closed even if it is a public holiday.
+		case isHoliday && h.IsClosure():
+			candidates = append(candidates, h)
+
+		case !isHoliday && h.HolidayCondition != v1.HolidayCondition_EXCLUSIVE:
+			candidates = append(candidates, h)
+		}
+	}
+
+	rank := func(h repo.OfficeHourModel) int {
+		r := h.Specificity() * 2
+		if isHoliday && h.HolidayCondition == v1.HolidayCondition_EXCLUSIVE {
+			r++
+		}
+
+		return r
+	}
+
+	best := -1
+	for _, h := range candidates {
+		best = max(best, rank(h))
+	}
+
+	validHours := make([]repo.OfficeHourModel, 0, 1)
+	for _, h := range candidates {
+		if rank(h) == best {
+			validHours = append(validHours, h)
+		}
+	}
+
+	slices.SortFunc(validHours, func(a, b repo.OfficeHourModel) int {
+		return strings.Compare(a.ID.Hex(), b.ID.Hex())
+	})
+
+	return validHours
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
 }
@@ -0,0 +1,754 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	commonv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/common/v1"
+	v1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/office_hours/v1"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testHour(id byte, mod func(m *repo.OfficeHourModel)) repo.OfficeHourModel {
+	m := repo.OfficeHourModel{
+		ID: primitive.ObjectID{11: id},
+		TimeRanges: []repo.DayTimeRange{
+			{
+				Start: repo.DayTime{Hours: 8},
+				End:   repo.DayTime{Hours: 12},
+			},
+		},
+	}
+
+	mod(&m)
+
+	return m
+}
+
+func TestSelectOfficeHours(t *testing.T) {
+	var (
+		monday = testHour(1, func(m *repo.OfficeHourModel) {
+			m.DayOfWeek = time.Monday
+		})
+		mondayHoliday = testHour(2, func(m *repo.OfficeHourModel) {
+			m.DayOfWeek = time.Monday
+			m.HolidayCondition = v1.HolidayCondition_INCLUDE
+		})
+		mondayHolidayOnly = testHour(3, func(m *repo.OfficeHourModel) {
+			m.DayOfWeek = time.Monday
+			m.HolidayCondition = v1.HolidayCondition_EXCLUSIVE
+		})
+		recurring = testHour(4, func(m *repo.OfficeHourModel) {
+			m.Date = "12-24"
+			m.HolidayCondition = v1.HolidayCondition_INCLUDE
+		})
+		specific = testHour(5, func(m *repo.OfficeHourModel) {
+			m.Date = "2029-12-24"
+			m.HolidayCondition = v1.HolidayCondition_INCLUDE
+		})
+		closure = testHour(6, func(m *repo.OfficeHourModel) {
+			m.Date = "2029-12-24"
+			m.TimeRanges = nil
+		})
+		otherMonday = testHour(0, func(m *repo.OfficeHourModel) {
+			m.DayOfWeek = time.Monday
+		})
+		vacation = testHour(7, func(m *repo.OfficeHourModel) {
+			m.DateRange = &repo.DateRange{From: "12-24", To: "01-06", Yearly: true}
+			m.TimeRanges = nil
+		})
+	)
+
+	cases := []struct {
+		name      string
+		hours     []repo.OfficeHourModel
+		isHoliday bool
+		expected  []repo.OfficeHourModel
+	}{
+		{
+			name:     "no office hours",
+			expected: nil,
+		},
+		{
+			name:     "weekday",
+			hours:    []repo.OfficeHourModel{monday},
+			expected: []repo.OfficeHourModel{monday},
+		},
+		{
+			name:      "weekday ignored on holidays",
+			hours:     []repo.OfficeHourModel{monday},
+			isHoliday: true,
+			expected:  nil,
+		},
+		{
+			name:      "exclusive holiday takes precedence",
+			hours:     []repo.OfficeHourModel{monday, mondayHoliday, mondayHolidayOnly},
+			isHoliday: true,
+			expected:  []repo.OfficeHourModel{mondayHolidayOnly},
+		},
+		{
+			name:     "exclusive holiday ignored on non-holidays",
+			hours:    []repo.OfficeHourModel{mondayHolidayOnly, monday},
+			expected: []repo.OfficeHourModel{monday},
+		},
+		{
+			name:     "recurring date overrides weekday",
+			hours:    []repo.OfficeHourModel{monday, recurring},
+			expected: []repo.OfficeHourModel{recurring},
+		},
+		{
+			name:      "specific date overrides recurring date",
+			hours:     []repo.OfficeHourModel{recurring, monday, specific},
+			isHoliday: true,
+			expected:  []repo.OfficeHourModel{specific},
+		},
+		{
+			name:     "closure overrides weekday",
+			hours:    []repo.OfficeHourModel{monday, closure},
+			expected: []repo.OfficeHourModel{closure},
+		},
+		{
+			name:      "closure applies on holidays",
+			hours:     []repo.OfficeHourModel{mondayHolidayOnly, closure},
+			isHoliday: true,
+			expected:  []repo.OfficeHourModel{closure},
+		},
+		{
+			name:      "date range closure applies on holidays",
+			hours:     []repo.OfficeHourModel{mondayHoliday, vacation},
+			isHoliday: true,
+			expected:  []repo.OfficeHourModel{vacation},
+		},
+		{
+			name:     "date range overrides weekday",
+			hours:    []repo.OfficeHourModel{monday, vacation},
+			expected: []repo.OfficeHourModel{vacation},
+		},
+		{
+			name:     "recurring date overrides date range",
+			hours:    []repo.OfficeHourModel{vacation, recurring},
+			expected: []repo.OfficeHourModel{recurring},
+		},
+		{
+			name:     "same specificity is ordered by name",
+			hours:    []repo.OfficeHourModel{monday, otherMonday},
+			expected: []repo.OfficeHourModel{otherMonday, monday},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := selectOfficeHours(c.hours, c.isHoliday)
+
+			if len(result) != len(c.expected) {
+				t.Fatalf("expected %d office hours but got %d", len(c.expected), len(result))
+			}
+
+			for idx, h := range result {
+				if h.ID != c.expected[idx].ID {
+					t.Errorf("expected office hour %d to be %s but got %s", idx, c.expected[idx].ID.Hex(), h.ID.Hex())
+				}
+			}
+		})
+	}
+}
+
+func TestClosureHasNoTimeRanges(t *testing.T) {
+	closure := repo.OfficeHourModel{
+		ID:   primitive.NewObjectID(),
+		Date: "2029-12-24",
+	}
+
+	if err := closure.Validate(); err != nil {
+		t.Fatalf("expected closure to be valid: %s", err)
+	}
+
+	result := selectOfficeHours([]repo.OfficeHourModel{closure}, false)
+	if len(result) != 1 {
+		t.Fatalf("expected the closure to be selected")
+	}
+
+	if len(result[0].TimeRanges) != 0 {
+		t.Errorf("expected closure to not have any time ranges")
+	}
+}
+
+func TestDateRange(t *testing.T) {
+	vacation := repo.OfficeHourModel{
+		ID:        primitive.NewObjectID(),
+		DateRange: &repo.DateRange{From: "12-24", To: "01-06", Yearly: true},
+	}
+
+	if err := vacation.Validate(); err != nil {
+		t.Fatalf("expected date range closure to be valid: %s", err)
+	}
+
+	for _, c := range []struct {
+		date    string
+		applies bool
+	}{
+		{"2029-12-23", false},
+		{"2029-12-24", true},
+		{"2029-12-31", true},
+		{"2030-01-06", true},
+		{"2030-01-07", false},
+	} {
+		d, _ := time.Parse("2006-01-02", c.date)
+		if vacation.AppliesAt(d) != c.applies {
+			t.Errorf("expected AppliesAt(%s) to be %t", c.date, c.applies)
+		}
+	}
+
+	renovation := repo.OfficeHourModel{
+		ID:        primitive.NewObjectID(),
+		DateRange: &repo.DateRange{From: "2029-12-30", To: "2030-01-10"},
+	}
+
+	if !renovation.ConflictsWith(&vacation) {
+		t.Errorf("expected overlapping date ranges to conflict")
+	}
+
+	renovation.DateRange = &repo.DateRange{From: "2030-01-07", To: "2030-01-10"}
+	if renovation.ConflictsWith(&vacation) {
+		t.Errorf("expected adjacent date ranges to not conflict")
+	}
+}
+
+func TestComputeOpenStateDST(t *testing.T) {
+	vienna, err := time.LoadLocation("Europe/Vienna")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %s", err)
+	}
+
+	hour := testHour(1, func(m *repo.OfficeHourModel) {
+		// Sunday is stored using the commonv1.DayOfWeek numbering
+		m.DayOfWeek = time.Weekday(commonv1.DayOfWeek_SUNDAY)
+		m.TimeRanges = []repo.DayTimeRange{
+			{
+				Start: repo.DayTime{Hours: 1},
+				End:   repo.DayTime{Hours: 4},
+			},
+		}
+	})
+
+	cases := []struct {
+		name      string
+		day       time.Time
+		now       time.Time
+		nextClose time.Time
+		openFor   time.Duration
+	}{
+		{
+			// clocks are turned forward from 02:00 to 03:00
+			name:      "start of daylight saving time",
+			day:       time.Date(2024, time.March, 31, 0, 0, 0, 0, vienna),
+			now:       time.Date(2024, time.March, 31, 0, 30, 0, 0, time.UTC),
+			nextClose: time.Date(2024, time.March, 31, 2, 0, 0, 0, time.UTC),
+			openFor:   2 * time.Hour,
+		},
+		{
+			// clocks are turned back from 03:00 to 02:00
+			name:      "end of daylight saving time",
+			day:       time.Date(2024, time.October, 27, 0, 0, 0, 0, vienna),
+			now:       time.Date(2024, time.October, 26, 23, 30, 0, 0, time.UTC),
+			nextClose: time.Date(2024, time.October, 27, 3, 0, 0, 0, time.UTC),
+			openFor:   4 * time.Hour,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if !hour.AppliesAt(c.now.In(vienna)) {
+				t.Fatalf("expected office hour to apply at %s", c.now.In(vienna))
+			}
+
+			days := []DayOfficeHours{
+				{
+					Date:        c.day,
+					OfficeHours: selectOfficeHours([]repo.OfficeHourModel{hour}, false),
+				},
+			}
+
+			state := computeOpenState(days, c.now.In(vienna))
+
+			if !state.Open {
+				t.Fatalf("expected to be open at %s", c.now.In(vienna))
+			}
+
+			if !state.NextClose.Equal(c.nextClose) {
+				t.Errorf("expected next close at %s but got %s", c.nextClose, state.NextClose.UTC())
+			}
+
+			start := hour.TimeRanges[0].Start
+			opensAt := time.Date(c.day.Year(), c.day.Month(), c.day.Day(), start.Hours, start.Minutes, start.Seconds, 0, vienna)
+
+			if d := state.NextClose.Sub(opensAt); d != c.openFor {
+				t.Errorf("expected to be open for %s but got %s", c.openFor, d)
+			}
+		})
+	}
+}
+
+func TestComputeOpenStateOvernight(t *testing.T) {
+	night := testHour(1, func(m *repo.OfficeHourModel) {
+		m.DayOfWeek = time.Monday
+		m.TimeRanges = []repo.DayTimeRange{
+			{
+				Start: repo.DayTime{Hours: 19},
+				End:   repo.DayTime{Hours: 7},
+			},
+		}
+	})
+
+	monday := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+	days := []DayOfficeHours{
+		{
+			Date:        monday,
+			OfficeHours: selectOfficeHours([]repo.OfficeHourModel{night}, false),
+		},
+		{
+			Date: monday.AddDate(0, 0, 1),
+		},
+	}
+
+	state := computeOpenState(days, time.Date(2024, time.June, 4, 2, 0, 0, 0, time.UTC))
+
+	if !state.Open {
+		t.Fatalf("expected to be open on Tuesday at 02:00")
+	}
+
+	if state.OfficeHour == nil || state.OfficeHour.ID != night.ID {
+		t.Errorf("expected the monday office hour to apply")
+	}
+
+	if expected := time.Date(2024, time.June, 4, 7, 0, 0, 0, time.UTC); !state.NextChange().Equal(expected) {
+		t.Errorf("expected next change at %s but got %s", expected, state.NextChange())
+	}
+
+	state = computeOpenState(days, time.Date(2024, time.June, 3, 18, 0, 0, 0, time.UTC))
+
+	if state.Open {
+		t.Fatalf("expected to be closed on Monday at 18:00")
+	}
+
+	if expected := time.Date(2024, time.June, 3, 19, 0, 0, 0, time.UTC); !state.NextChange().Equal(expected) {
+		t.Errorf("expected next change at %s but got %s", expected, state.NextChange())
+	}
+}
+
+func TestComputeOpenStateModes(t *testing.T) {
+	hour := testHour(1, func(m *repo.OfficeHourModel) {
+		m.DayOfWeek = time.Monday
+		m.TimeRanges = []repo.DayTimeRange{
+			{
+				Start: repo.DayTime{Hours: 8},
+				End:   repo.DayTime{Hours: 12},
+			},
+			{
+				Start: repo.DayTime{Hours: 12},
+				End:   repo.DayTime{Hours: 18},
+				Mode:  repo.ModePhoneOnly,
+			},
+		}
+	})
+
+	days := []DayOfficeHours{
+		{
+			Date:        time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC),
+			OfficeHours: []repo.OfficeHourModel{hour},
+		},
+	}
+
+	state := computeOpenState(days, time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC))
+
+	if state.Mode != repo.ModeConsultation {
+		t.Errorf("expected mode %q but got %q", repo.ModeConsultation, state.Mode)
+	}
+
+	if expected := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC); !state.NextTransition.Equal(expected) {
+		t.Errorf("expected next transition at %s but got %s", expected, state.NextTransition)
+	}
+
+	// phone-only time ranges do not open a location
+	if expected := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC); !state.NextClose.Equal(expected) {
+		t.Errorf("expected next close at %s but got %s", expected, state.NextClose)
+	}
+
+	state = computeOpenState(days, time.Date(2024, time.June, 3, 13, 0, 0, 0, time.UTC))
+
+	if state.Open || state.Mode != repo.ModePhoneOnly || state.OfficeHour != nil {
+		t.Errorf("expected to be closed with mode %q but got open=%v mode=%q", repo.ModePhoneOnly, state.Open, state.Mode)
+	}
+
+	if expected := time.Date(2024, time.June, 3, 18, 0, 0, 0, time.UTC); !state.NextTransition.Equal(expected) {
+		t.Errorf("expected next transition at %s but got %s", expected, state.NextTransition)
+	}
+
+	state = computeOpenState(days, time.Date(2024, time.June, 3, 19, 0, 0, 0, time.UTC))
+
+	if state.Open || state.Mode != "" {
+		t.Errorf("expected to be closed without mode but got open=%v mode=%q", state.Open, state.Mode)
+	}
+
+	// a location forced closed may still be reachable by phone
+	until := time.Date(2024, time.June, 3, 11, 0, 0, 0, time.UTC)
+	state = applyOverride(days, time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC), &repo.Override{
+		Mode:      repo.ModePhoneOnly,
+		Until:     &until,
+		CreatedAt: time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC),
+	})
+
+	if state.Open || state.Mode != repo.ModePhoneOnly || !state.NextOpen.Equal(until) {
+		t.Errorf("expected to be closed with mode %q until %s but got open=%v mode=%q next open=%s", repo.ModePhoneOnly, until, state.Open, state.Mode, state.NextOpen)
+	}
+}
+
+type noHolidays struct{}
+
+func (noHolidays) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	return nil, nil
+}
+
+type countingHolidays struct {
+	calls int
+}
+
+func (p *countingHolidays) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	p.calls++
+
+	return []string{fmt.Sprintf("%04d-%02d-01", year, month)}, nil
+}
+
+func TestHolidayCache(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		ttl      time.Duration
+		run      func(r *Resolver, now *time.Time)
+		expected int
+	}{
+		{
+			name:     "cached within ttl",
+			ttl:      time.Hour,
+			run:      func(r *Resolver, now *time.Time) { *now = now.Add(59 * time.Minute) },
+			expected: 1,
+		},
+		{
+			name:     "refetched after expiry",
+			ttl:      time.Hour,
+			run:      func(r *Resolver, now *time.Time) { *now = now.Add(time.Hour) },
+			expected: 2,
+		},
+		{
+			name:     "refetched after invalidation",
+			ttl:      time.Hour,
+			run:      func(r *Resolver, now *time.Time) { r.InvalidateHolidayCache() },
+			expected: 2,
+		},
+		{
+			name:     "caching disabled",
+			ttl:      0,
+			run:      func(r *Resolver, now *time.Time) {},
+			expected: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := new(countingHolidays)
+			now := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+			r := NewResolver(nil, provider, c.ttl, time.UTC)
+			r.cache.now = func() time.Time { return now }
+
+			for idx := 0; idx < 2; idx++ {
+				if idx > 0 {
+					c.run(r, &now)
+				}
+
+				isHoliday, err := r.isHoliday(ctx, day)
+				if err != nil || !isHoliday {
+					t.Fatalf("expected %s to be a holiday (%v)", day, err)
+				}
+			}
+
+			if provider.calls != c.expected {
+				t.Errorf("expected %d provider calls but got %d", c.expected, provider.calls)
+			}
+		})
+	}
+}
+
+// recoveringHolidays returns degraded holidays until recovered is set.
+type recoveringHolidays struct {
+	recovered bool
+	calls     int
+}
+
+func (p *recoveringHolidays) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	p.calls++
+
+	if !p.recovered {
+		return nil, fmt.Errorf("%w: calendar unavailable", ErrDegraded)
+	}
+
+	return []string{fmt.Sprintf("%04d-%02d-01", year, month)}, nil
+}
+
+func TestHolidayCacheDegraded(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)
+
+	provider := new(recoveringHolidays)
+	now := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	r := NewResolver(nil, provider, 6*time.Hour, time.UTC)
+	r.cache.now = func() time.Time { return now }
+
+	// the degraded result is used but does not fail the lookup
+	isHoliday, err := r.isHoliday(ctx, day)
+	if err != nil || isHoliday {
+		t.Fatalf("expected the degraded result to be used, got %v (%v)", isHoliday, err)
+	}
+
+	// the calendar recovers well within the cache ttl
+	provider.recovered = true
+	now = now.Add(degradedCacheTTL)
+
+	isHoliday, err = r.isHoliday(ctx, day)
+	if err != nil || !isHoliday {
+		t.Fatalf("expected the recovered result to be used, got %v (%v)", isHoliday, err)
+	}
+
+	// the recovered result is cached for the full ttl
+	now = now.Add(5 * time.Hour)
+
+	if _, err := r.isHoliday(ctx, day); err != nil {
+		t.Fatalf("failed to check holiday: %s", err)
+	}
+
+	if provider.calls != 2 {
+		t.Errorf("expected 2 provider calls but got %d", provider.calls)
+	}
+}
+
+func TestResolveOpenStateAfterOverride(t *testing.T) {
+	ctx := context.Background()
+	store := repo.NewMemoryStore()
+
+	if _, err := store.UpsertOfficeHours(ctx, &repo.OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []repo.DayTimeRange{
+			{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	r := NewResolver(store, noHolidays{}, 0, time.UTC)
+
+	// overrides are only kept for some time after they ended so use the
+	// next monday
+	monday := startOfDay(time.Now().UTC()).AddDate(0, 0, 1)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, 1)
+	}
+
+	now := monday.Add(10*time.Hour + 30*time.Minute)
+	revokedAt := monday.Add(10 * time.Hour)
+
+	if err := store.SetOverride(ctx, &repo.Override{
+		CreatedAt: monday.Add(9 * time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to set override: %s", err)
+	}
+
+	if err := store.RevokeOverride(ctx, "", revokedAt); err != nil {
+		t.Fatalf("failed to revoke override: %s", err)
+	}
+
+	if err := store.RevokeOverride(ctx, "", now); err != repo.ErrNotFound {
+		t.Fatalf("expected ErrNotFound when revoking twice, got %v", err)
+	}
+
+	state, err := r.ResolveOpenState(ctx, now, "", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if !state.Open || state.Override != nil {
+		t.Fatalf("expected the regular office hours to apply again")
+	}
+
+	// the regular state began once the override was revoked
+	if !state.Since.Equal(revokedAt) {
+		t.Errorf("expected the state to begin at %s but got %s", revokedAt, state.Since)
+	}
+}
+
+type fixedHolidays []string
+
+func (h fixedHolidays) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	return h, nil
+}
+
+func TestResolveOfficeHoursBetween(t *testing.T) {
+	ctx := context.Background()
+	store := repo.NewMemoryStore()
+
+	morning := []repo.DayTimeRange{
+		{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}},
+	}
+
+	hours := make(map[time.Weekday]*repo.OfficeHourModel)
+	for _, m := range []*repo.OfficeHourModel{
+		{DayOfWeek: time.Monday, TimeRanges: morning},
+		{DayOfWeek: time.Tuesday, TimeRanges: morning},
+		{DayOfWeek: time.Wednesday, TimeRanges: morning},
+		{DayOfWeek: time.Thursday, TimeRanges: []repo.DayTimeRange{
+			{Start: repo.DayTime{Hours: 19}, End: repo.DayTime{Hours: 7}},
+		}},
+	} {
+		created, err := store.UpsertOfficeHours(ctx, m)
+		if err != nil {
+			t.Fatalf("failed to upsert office hour: %s", err)
+		}
+
+		hours[m.DayOfWeek] = created
+	}
+
+	// overrides are only kept for some time after they ended so use the
+	// next monday
+	monday := startOfDay(time.Now().UTC()).AddDate(0, 0, 1)
+	for monday.Weekday() != time.Monday {
+		monday = monday.AddDate(0, 0, 1)
+	}
+
+	wednesday := monday.AddDate(0, 0, 2)
+	friday := monday.AddDate(0, 0, 4)
+
+	r := NewResolver(store, fixedHolidays{wednesday.Format("2006-01-02")}, 0, time.UTC)
+
+	days, err := r.ResolveOfficeHoursBetween(ctx, monday.Add(10*time.Hour), friday.Add(10*time.Hour), "")
+	if err != nil {
+		t.Fatalf("failed to resolve office hours: %s", err)
+	}
+
+	if len(days) != 5 {
+		t.Fatalf("expected 5 days but got %d", len(days))
+	}
+
+	for idx, day := range days {
+		if expected := monday.AddDate(0, 0, idx); !day.Date.Equal(expected) {
+			t.Errorf("expected day %d to start at %s but got %s", idx, expected, day.Date)
+		}
+
+		var expected []primitive.ObjectID
+		switch day.Date.Weekday() {
+		case time.Wednesday, time.Friday:
+			// weekdays do not apply on public holidays
+		default:
+			expected = append(expected, hours[day.Date.Weekday()].ID)
+		}
+
+		if len(day.OfficeHours) != len(expected) {
+			t.Errorf("expected %d office hours on %s but got %d", len(expected), day.Date.Weekday(), len(day.OfficeHours))
+			continue
+		}
+
+		for hIdx, h := range day.OfficeHours {
+			if h.ID != expected[hIdx] {
+				t.Errorf("unexpected office hour %s on %s", h.ID.Hex(), day.Date.Weekday())
+			}
+		}
+	}
+
+	// the overnight range of thursday is still open on friday
+	state, err := r.ResolveOpenState(ctx, friday.Add(2*time.Hour), "", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if !state.Open || state.OfficeHour == nil || state.OfficeHour.ID != hours[time.Thursday].ID {
+		t.Fatalf("expected to be open on friday at 02:00 by the thursday office hour")
+	}
+
+	if expected := friday.Add(7 * time.Hour); !state.NextClose.Equal(expected) {
+		t.Errorf("expected next close at %s but got %s", expected, state.NextClose)
+	}
+
+	// the next opening skips the public holiday
+	state, err = r.ResolveOpenState(ctx, monday.AddDate(0, 0, 1).Add(13*time.Hour), "", 72*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if expected := monday.AddDate(0, 0, 3).Add(19 * time.Hour); state.Open || !state.NextOpen.Equal(expected) {
+		t.Errorf("expected to be closed until %s but got open=%v next open=%s", expected, state.Open, state.NextOpen)
+	}
+
+	// an override closes the location within the window
+	until := monday.AddDate(0, 0, 1).Add(10 * time.Hour)
+	if err := store.SetOverride(ctx, &repo.Override{
+		Until:     &until,
+		CreatedAt: monday.AddDate(0, 0, 1).Add(9 * time.Hour),
+	}); err != nil {
+		t.Fatalf("failed to set override: %s", err)
+	}
+
+	state, err = r.ResolveOpenState(ctx, monday.AddDate(0, 0, 1).Add(9*time.Hour+30*time.Minute), "", 24*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if state.Open || state.Override == nil || !state.NextOpen.Equal(until) {
+		t.Errorf("expected to be closed by the override until %s but got open=%v next open=%s", until, state.Open, state.NextOpen)
+	}
+}
+
+func TestResolveOpenStateHorizon(t *testing.T) {
+	ctx := context.Background()
+	store := repo.NewMemoryStore()
+
+	for _, day := range []time.Weekday{time.Monday, time.Tuesday} {
+		if _, err := store.UpsertOfficeHours(ctx, &repo.OfficeHourModel{
+			DayOfWeek: day,
+			TimeRanges: []repo.DayTimeRange{
+				{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}},
+			},
+		}); err != nil {
+			t.Fatalf("failed to upsert office hour: %s", err)
+		}
+	}
+
+	saturday := time.Date(2024, time.June, 8, 10, 0, 0, 0, time.UTC)
+	tuesday := time.Date(2024, time.June, 11, 8, 0, 0, 0, time.UTC)
+
+	// closed over the weekend and on the public holiday on monday
+	r := NewResolver(store, fixedHolidays{"2024-06-10"}, 0, time.UTC)
+
+	// the next opening on tuesday is beyond the horizon
+	state, err := r.ResolveOpenState(ctx, saturday, "", 48*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if state.Open || !state.NextOpen.IsZero() || !state.NextClose.IsZero() || !state.NextChange().IsZero() {
+		t.Errorf("expected no change within the horizon, got open=%v next open=%s next close=%s", state.Open, state.NextOpen, state.NextClose)
+	}
+
+	// it is found once the horizon includes tuesday
+	state, err = r.ResolveOpenState(ctx, saturday, "", 72*time.Hour)
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if !state.NextOpen.Equal(tuesday) {
+		t.Errorf("expected the next opening at %s but got %s", tuesday, state.NextOpen)
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/apis/pkg/auth"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// NewActorInterceptor returns an interceptor that attaches the authenticated
+// user to the request context so mutations can be recorded in the audit log.
+//
+// The user is taken from the auth-annotation interceptor or the interceptor
+// returned by NewExtensionAuthInterceptor so it must be installed after them.
+// Requests that have not been authenticated do not carry an actor.
+func NewActorInterceptor() connect.Interceptor {
+	return actorInterceptor{}
+}
+
+type actorInterceptor struct{}
+
+func (actorInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		return next(withActor(ctx), req)
+	}
+}
+
+func (actorInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (actorInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+func withActor(ctx context.Context) context.Context {
+	user := auth.From(ctx)
+
+	if user == nil {
+		user = userFrom(ctx)
+	}
+
+	if user == nil {
+		return ctx
+	}
+
+	return repo.WithActor(ctx, repo.Actor{
+		ID:          user.ID,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+	})
+}
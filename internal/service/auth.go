@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/apis/pkg/auth"
+)
+
+// accessLevel defines who may call a procedure of the extension service.
+type accessLevel int
+
+const (
+	// accessAdmin requires a user with one of the configured admin roles.
+	accessAdmin accessLevel = iota
+
+	// accessAuthenticated requires an authenticated user.
+	accessAuthenticated
+
+	// accessPublic does not require authentication.
+	accessPublic
+)
+
+// extensionProcedureAccess defines the access level of the procedures of
+// the extension service. Procedures that are not listed require an admin.
+var extensionProcedureAccess = map[string]accessLevel{
+	ExtensionServiceListExtendedOfficeHoursProcedure: accessPublic,
+	ExtensionServiceOpeningRangesProcedure:           accessPublic,
+	ExtensionServiceNextChangeProcedure:              accessPublic,
+	ExtensionServiceWatchOpenStateProcedure:          accessPublic,
+	ExtensionServiceGetOverrideProcedure:             accessPublic,
+
+	// the history, drafts and the trash may be inspected by all users
+	ExtensionServiceListAuditEntriesProcedure:       accessAuthenticated,
+	ExtensionServiceListScheduleVersionsProcedure:   accessAuthenticated,
+	ExtensionServiceDiffScheduleVersionsProcedure:   accessAuthenticated,
+	ExtensionServiceGetDraftProcedure:               accessAuthenticated,
+	ExtensionServiceListDraftsProcedure:             accessAuthenticated,
+	ExtensionServiceExportScheduleProcedure:         accessAuthenticated,
+	ExtensionServiceListDeletedOfficeHoursProcedure: accessAuthenticated,
+}
+
+type userContextKey struct{}
+
+// userFrom returns the user authenticated by the interceptor returned by
+// NewExtensionAuthInterceptor or nil.
+func userFrom(ctx context.Context) *auth.RemoteUser {
+	user, _ := ctx.Value(userContextKey{}).(*auth.RemoteUser)
+
+	return user
+}
+
+// NewExtensionAuthInterceptor returns an interceptor that authenticates
+// callers of the extension service. Since the extension service uses JSON
+// encoded messages, the auth-annotation interceptor cannot be used.
+//
+// Users are extracted from the remote-user headers set by the authentication
+// proxy and each procedure is checked against its access level in
+// extensionProcedureAccess. Procedures that modify office hours require one
+// of adminRoles, given as role IDs or, if roleResolver is set, role names.
+func NewExtensionAuthInterceptor(adminRoles []string, roleResolver auth.RoleResolverFunc) connect.Interceptor {
+	return &extensionAuthInterceptor{
+		adminRoles:   adminRoles,
+		roleResolver: roleResolver,
+	}
+}
+
+type extensionAuthInterceptor struct {
+	adminRoles   []string
+	roleResolver auth.RoleResolverFunc
+}
+
+func (i *extensionAuthInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, err := i.authenticate(ctx, req.Spec().Procedure, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return next(ctx, req)
+	}
+}
+
+func (*extensionAuthInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *extensionAuthInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		// auth.RemoteHeaderExtractor only inspects the request headers so
+		// wrap them in an empty request.
+		req := connect.NewRequest(&struct{}{})
+		for key, values := range conn.RequestHeader() {
+			req.Header()[key] = values
+		}
+
+		ctx, err := i.authenticate(ctx, conn.Spec().Procedure, req)
+		if err != nil {
+			return err
+		}
+
+		return next(ctx, conn)
+	}
+}
+
+// authenticate returns a context that carries the user of req and rejects
+// calls of procedures the user is not allowed to call.
+func (i *extensionAuthInterceptor) authenticate(ctx context.Context, procedure string, req connect.AnyRequest) (context.Context, error) {
+	user, err := auth.RemoteHeaderExtractor(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	level := extensionProcedureAccess[procedure]
+
+	if user.ID == "" {
+		if level != accessPublic {
+			return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("authentication required"))
+		}
+
+		return ctx, nil
+	}
+
+	if level == accessAdmin {
+		admin, err := i.isAdmin(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+
+		if !admin {
+			return nil, connect.NewError(connect.CodePermissionDenied, errors.New("you're not allowed to perform this operation"))
+		}
+
+		user.Admin = true
+	}
+
+	return context.WithValue(ctx, userContextKey{}, &user), nil
+}
+
+// isAdmin reports whether user has one of the admin roles assigned.
+func (i *extensionAuthInterceptor) isAdmin(ctx context.Context, user auth.RemoteUser) (bool, error) {
+	for _, id := range user.RoleIDs {
+		if slices.Contains(i.adminRoles, id) {
+			return true, nil
+		}
+	}
+
+	if i.roleResolver == nil {
+		return false, nil
+	}
+
+	// admin roles may be configured using their names as well
+	for _, id := range user.RoleIDs {
+		role, err := i.roleResolver(ctx, id)
+		if err != nil {
+			return false, fmt.Errorf("failed to resolve role %q: %w", id, err)
+		}
+
+		if slices.Contains(i.adminRoles, role.Name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/connect-go"
+	idmv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/idm/v1"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+func TestExtensionAuthInterceptor(t *testing.T) {
+	// role names by ID
+	roles := map[string]string{
+		"role-1": "office_hours_manager",
+		"role-2": "employee",
+	}
+
+	resolveRole := func(_ context.Context, id string) (*idmv1.Role, error) {
+		return &idmv1.Role{Id: id, Name: roles[id]}, nil
+	}
+
+	cases := []struct {
+		name      string
+		procedure string
+		userID    string
+		roles     []string
+		code      connect.Code
+		actor     string
+	}{
+		{"public anonymous", ExtensionServiceNextChangeProcedure, "", nil, 0, ""},
+		{"public authenticated", ExtensionServiceNextChangeProcedure, "alice", nil, 0, "alice"},
+		{"write anonymous", ExtensionServiceImportScheduleProcedure, "", nil, connect.CodeUnauthenticated, ""},
+		{"write without role", ExtensionServiceSetOverrideProcedure, "alice", []string{"role-2"}, connect.CodePermissionDenied, ""},
+		{"write admin by id", ExtensionServiceSetOverrideProcedure, "alice", []string{"role-0"}, 0, "alice"},
+		{"write admin by name", ExtensionServiceRestoreOfficeHourProcedure, "alice", []string{"role-2", "role-1"}, 0, "alice"},
+		{"audit log anonymous", ExtensionServiceListAuditEntriesProcedure, "", nil, connect.CodeUnauthenticated, ""},
+		{"audit log authenticated", ExtensionServiceListAuditEntriesProcedure, "alice", nil, 0, "alice"},
+		{"unknown procedure", "/" + ExtensionServiceName + "/Unknown", "alice", nil, connect.CodePermissionDenied, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := connect.NewRequest(&struct{}{})
+			if c.userID != "" {
+				req.Header().Set("X-Remote-User-ID", c.userID)
+			}
+
+			for _, role := range c.roles {
+				req.Header().Add("X-Remote-Role", role)
+			}
+
+			var actor repo.Actor
+			next := func(ctx context.Context, _ connect.AnyRequest) (connect.AnyResponse, error) {
+				actor = repo.ActorFrom(ctx)
+
+				return nil, nil
+			}
+
+			handler := NewExtensionAuthInterceptor([]string{"role-0", "office_hours_manager"}, resolveRole).WrapUnary(NewActorInterceptor().WrapUnary(next))
+
+			_, err := handler(context.Background(), &procedureRequest{AnyRequest: req, procedure: c.procedure})
+
+			if c.code != 0 {
+				if connect.CodeOf(err) != c.code {
+					t.Fatalf("expected code %s but got %v", c.code, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if actor.ID != c.actor {
+				t.Errorf("expected actor %q but got %q", c.actor, actor.ID)
+			}
+		})
+	}
+}
+
+// procedureRequest overwrites the procedure of a request.
+type procedureRequest struct {
+	connect.AnyRequest
+	procedure string
+}
+
+func (r *procedureRequest) Spec() connect.Spec {
+	spec := r.AnyRequest.Spec()
+	spec.Procedure = r.procedure
+
+	return spec
+}
@@ -0,0 +1,20 @@
+package service
+
+import "encoding/json"
+
+// jsonCodec is a connect.Codec that marshals plain Go types using
+// encoding/json. It is used by the extension service for RPCs that are
+// not part of the tkd.office_hours.v1 protobuf definitions.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
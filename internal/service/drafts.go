@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (svc *Service) CreateDraft(ctx context.Context, req *connect.Request[CreateDraftRequest]) (*connect.Response[repo.Draft], error) {
+	draft, err := svc.repo.CreateDraft(ctx, req.Msg.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(draft), nil
+}
+
+func (svc *Service) GetDraft(ctx context.Context, req *connect.Request[GetDraftRequest]) (*connect.Response[repo.Draft], error) {
+	draft, err := svc.repo.GetDraft(ctx, req.Msg.ID)
+	if err != nil {
+		return nil, draftError(err)
+	}
+
+	return connect.NewResponse(draft), nil
+}
+
+func (svc *Service) ListDrafts(ctx context.Context, req *connect.Request[ListDraftsRequest]) (*connect.Response[ListDraftsResponse], error) {
+	drafts, err := svc.repo.ListDrafts(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&ListDraftsResponse{
+		Drafts: drafts,
+	}), nil
+}
+
+func (svc *Service) UpsertDraftOfficeHour(ctx context.Context, req *connect.Request[UpsertDraftOfficeHourRequest]) (*connect.Response[repo.Draft], error) {
+	hour := req.Msg.OfficeHour
+
+	draft, err := svc.repo.GetDraft(ctx, req.Msg.Draft)
+	if err != nil {
+		return nil, draftError(err)
+	}
+
+	if hour.ID.IsZero() {
+		hour.ID = primitive.NewObjectIDFromTimestamp(time.Now())
+	}
+
+	if err := validateOfficeHour(&hour, draft.OfficeHours); err != nil {
+		return nil, err
+	}
+
+	replaced := false
+	for idx, existing := range draft.OfficeHours {
+		if existing.ID == hour.ID {
+			draft.OfficeHours[idx] = hour
+			replaced = true
+			break
+		}
+	}
+
+	if !replaced {
+		draft.OfficeHours = append(draft.OfficeHours, hour)
+	}
+
+	if err := svc.repo.UpdateDraft(ctx, draft); err != nil {
+		return nil, draftError(err)
+	}
+
+	return connect.NewResponse(draft), nil
+}
+
+func (svc *Service) DeleteDraftOfficeHour(ctx context.Context, req *connect.Request[DeleteDraftOfficeHourRequest]) (*connect.Response[repo.Draft], error) {
+	draft, err := svc.repo.GetDraft(ctx, req.Msg.Draft)
+	if err != nil {
+		return nil, draftError(err)
+	}
+
+	found := false
+	for idx, existing := range draft.OfficeHours {
+		if existing.ID.Hex() == req.Msg.OfficeHour {
+			draft.OfficeHours = append(draft.OfficeHours[:idx], draft.OfficeHours[idx+1:]...)
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil, connect.NewError(connect.CodeNotFound, repo.ErrNotFound)
+	}
+
+	if err := svc.repo.UpdateDraft(ctx, draft); err != nil {
+		return nil, draftError(err)
+	}
+
+	return connect.NewResponse(draft), nil
+}
+
+func (svc *Service) PublishDraft(ctx context.Context, req *connect.Request[PublishDraftRequest]) (*connect.Response[PublishDraftResponse], error) {
+	if req.Msg.At != nil {
+		if !req.Msg.At.After(time.Now()) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("at must be in the future"))
+		}
+
+		draft, err := svc.repo.GetDraft(ctx, req.Msg.ID)
+		if err != nil {
+			return nil, draftError(err)
+		}
+
+		draft.PublishAt = req.Msg.At
+
+		if err := svc.repo.UpdateDraft(ctx, draft); err != nil {
+			return nil, draftError(err)
+		}
+
+		svc.providers.Drafts.Trigger()
+
+		return connect.NewResponse(&PublishDraftResponse{
+			Draft: draft,
+		}), nil
+	}
+
+	version, err := svc.repo.PublishDraft(ctx, req.Msg.ID)
+	if err != nil {
+		return nil, draftError(err)
+	}
+
+	defer svc.providers.Watcher.Trigger()
+
+	draft, err := svc.repo.GetDraft(ctx, req.Msg.ID)
+	if err != nil {
+		return nil, draftError(err)
+	}
+
+	return connect.NewResponse(&PublishDraftResponse{
+		Version: version,
+		Draft:   draft,
+	}), nil
+}
+
+func (svc *Service) DeleteDraft(ctx context.Context, req *connect.Request[DeleteDraftRequest]) (*connect.Response[DeleteDraftResponse], error) {
+	if err := svc.repo.DeleteDraft(ctx, req.Msg.ID); err != nil {
+		return nil, draftError(err)
+	}
+
+	return connect.NewResponse(&DeleteDraftResponse{}), nil
+}
+
+// draftError converts draft related errors returned by the repo to connect
+// errors.
+func draftError(err error) error {
+	switch {
+	case errors.Is(err, repo.ErrDraftNotFound):
+		return connect.NewError(connect.CodeNotFound, err)
+
+	case errors.Is(err, repo.ErrDraftPublished):
+		return connect.NewError(connect.CodeFailedPrecondition, err)
+	}
+
+	return upsertError(err)
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/connect-go"
+)
+
+// ExtensionServiceName is the fully-qualified name of the office-hour
+// extension service. It serves RPCs that are not (yet) part of the
+// OfficeHourService protobuf definition using JSON encoded messages.
+const ExtensionServiceName = "tkd.office_hours.v1.OfficeHourExtensionService"
+
+const (
+	// ExtensionServiceListExtendedOfficeHoursProcedure is the fully-qualified
+	// name of the ListExtendedOfficeHours RPC. Use it instead of ListHours to
+	// get the whole period of date-range office hours.
+	ExtensionServiceListExtendedOfficeHoursProcedure = "/" + ExtensionServiceName + "/ListExtendedOfficeHours"
+
+	// ExtensionServiceUpsertExtendedOfficeHourProcedure is the fully-qualified
+	// name of the UpsertExtendedOfficeHour RPC.
+	ExtensionServiceUpsertExtendedOfficeHourProcedure = "/" + ExtensionServiceName + "/UpsertExtendedOfficeHour"
+
+	// ExtensionServiceOpeningRangesProcedure is the fully-qualified name of the
+	// OpeningRanges RPC.
+	ExtensionServiceOpeningRangesProcedure = "/" + ExtensionServiceName + "/OpeningRanges"
+
+	// ExtensionServiceNextChangeProcedure is the fully-qualified name of the
+	// NextChange RPC.
+	ExtensionServiceNextChangeProcedure = "/" + ExtensionServiceName + "/NextChange"
+
+	// ExtensionServiceWatchOpenStateProcedure is the fully-qualified name of
+	// the server-streaming WatchOpenState RPC.
+	ExtensionServiceWatchOpenStateProcedure = "/" + ExtensionServiceName + "/WatchOpenState"
+
+	// ExtensionServiceSetOverrideProcedure is the fully-qualified name of the
+	// SetOverride RPC.
+	ExtensionServiceSetOverrideProcedure = "/" + ExtensionServiceName + "/SetOverride"
+
+	// ExtensionServiceGetOverrideProcedure is the fully-qualified name of the
+	// GetOverride RPC.
+	ExtensionServiceGetOverrideProcedure = "/" + ExtensionServiceName + "/GetOverride"
+
+	// ExtensionServiceRevokeOverrideProcedure is the fully-qualified name of
+	// the RevokeOverride RPC.
+	ExtensionServiceRevokeOverrideProcedure = "/" + ExtensionServiceName + "/RevokeOverride"
+
+	// ExtensionServiceListAuditEntriesProcedure is the fully-qualified name of
+	// the ListAuditEntries RPC.
+	ExtensionServiceListAuditEntriesProcedure = "/" + ExtensionServiceName + "/ListAuditEntries"
+
+	// ExtensionServiceListScheduleVersionsProcedure is the fully-qualified
+	// name of the ListScheduleVersions RPC.
+	ExtensionServiceListScheduleVersionsProcedure = "/" + ExtensionServiceName + "/ListScheduleVersions"
+
+	// ExtensionServiceDiffScheduleVersionsProcedure is the fully-qualified
+	// name of the DiffScheduleVersions RPC.
+	ExtensionServiceDiffScheduleVersionsProcedure = "/" + ExtensionServiceName + "/DiffScheduleVersions"
+
+	// ExtensionServiceRollbackScheduleProcedure is the fully-qualified name
+	// of the RollbackSchedule RPC.
+	ExtensionServiceRollbackScheduleProcedure = "/" + ExtensionServiceName + "/RollbackSchedule"
+
+	// ExtensionServiceCreateDraftProcedure is the fully-qualified name of the
+	// CreateDraft RPC.
+	ExtensionServiceCreateDraftProcedure = "/" + ExtensionServiceName + "/CreateDraft"
+
+	// ExtensionServiceGetDraftProcedure is the fully-qualified name of the
+	// GetDraft RPC.
+	ExtensionServiceGetDraftProcedure = "/" + ExtensionServiceName + "/GetDraft"
+
+	// ExtensionServiceListDraftsProcedure is the fully-qualified name of the
+	// ListDrafts RPC.
+	ExtensionServiceListDraftsProcedure = "/" + ExtensionServiceName + "/ListDrafts"
+
+	// ExtensionServiceUpsertDraftOfficeHourProcedure is the fully-qualified
+	// name of the UpsertDraftOfficeHour RPC.
+	ExtensionServiceUpsertDraftOfficeHourProcedure = "/" + ExtensionServiceName + "/UpsertDraftOfficeHour"
+
+	// ExtensionServiceDeleteDraftOfficeHourProcedure is the fully-qualified
+	// name of the DeleteDraftOfficeHour RPC.
+	ExtensionServiceDeleteDraftOfficeHourProcedure = "/" + ExtensionServiceName + "/DeleteDraftOfficeHour"
+
+	// ExtensionServicePublishDraftProcedure is the fully-qualified name of the
+	// PublishDraft RPC.
+	ExtensionServicePublishDraftProcedure = "/" + ExtensionServiceName + "/PublishDraft"
+
+	// ExtensionServiceDeleteDraftProcedure is the fully-qualified name of the
+	// DeleteDraft RPC.
+	ExtensionServiceDeleteDraftProcedure = "/" + ExtensionServiceName + "/DeleteDraft"
+
+	// ExtensionServiceExportScheduleProcedure is the fully-qualified name of
+	// the ExportSchedule RPC.
+	ExtensionServiceExportScheduleProcedure = "/" + ExtensionServiceName + "/ExportSchedule"
+
+	// ExtensionServiceImportScheduleProcedure is the fully-qualified name of
+	// the ImportSchedule RPC.
+	ExtensionServiceImportScheduleProcedure = "/" + ExtensionServiceName + "/ImportSchedule"
+
+	// ExtensionServiceListDeletedOfficeHoursProcedure is the fully-qualified
+	// name of the ListDeletedOfficeHours RPC.
+	ExtensionServiceListDeletedOfficeHoursProcedure = "/" + ExtensionServiceName + "/ListDeletedOfficeHours"
+
+	// ExtensionServiceRestoreOfficeHourProcedure is the fully-qualified name
+	// of the RestoreOfficeHour RPC.
+	ExtensionServiceRestoreOfficeHourProcedure = "/" + ExtensionServiceName + "/RestoreOfficeHour"
+
+	// ExtensionServiceInvalidateHolidayCacheProcedure is the fully-qualified
+	// name of the InvalidateHolidayCache RPC.
+	ExtensionServiceInvalidateHolidayCacheProcedure = "/" + ExtensionServiceName + "/InvalidateHolidayCache"
+)
+
+// NewExtensionServiceHandler builds an HTTP handler for the extension service
+// served by svc. It returns the path on which to mount the handler and the
+// handler itself.
+//
+// Note that the extension service only supports the JSON codec so
+// interceptors that expect protobuf messages must not be passed in opts.
+func NewExtensionServiceHandler(svc *Service, opts ...connect.HandlerOption) (string, http.Handler) {
+	opts = append(opts, connect.WithCodec(jsonCodec{}))
+
+	mux := http.NewServeMux()
+
+	mux.Handle(ExtensionServiceListExtendedOfficeHoursProcedure, connect.NewUnaryHandler(
+		ExtensionServiceListExtendedOfficeHoursProcedure,
+		svc.ListExtendedOfficeHours,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceUpsertExtendedOfficeHourProcedure, connect.NewUnaryHandler(
+		ExtensionServiceUpsertExtendedOfficeHourProcedure,
+		svc.UpsertExtendedOfficeHour,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceOpeningRangesProcedure, connect.NewUnaryHandler(
+		ExtensionServiceOpeningRangesProcedure,
+		svc.OpeningRanges,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceNextChangeProcedure, connect.NewUnaryHandler(
+		ExtensionServiceNextChangeProcedure,
+		svc.NextChange,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceWatchOpenStateProcedure, connect.NewServerStreamHandler(
+		ExtensionServiceWatchOpenStateProcedure,
+		svc.WatchOpenState,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceSetOverrideProcedure, connect.NewUnaryHandler(
+		ExtensionServiceSetOverrideProcedure,
+		svc.SetOverride,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceGetOverrideProcedure, connect.NewUnaryHandler(
+		ExtensionServiceGetOverrideProcedure,
+		svc.GetOverride,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceRevokeOverrideProcedure, connect.NewUnaryHandler(
+		ExtensionServiceRevokeOverrideProcedure,
+		svc.RevokeOverride,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceListAuditEntriesProcedure, connect.NewUnaryHandler(
+		ExtensionServiceListAuditEntriesProcedure,
+		svc.ListAuditEntries,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceListScheduleVersionsProcedure, connect.NewUnaryHandler(
+		ExtensionServiceListScheduleVersionsProcedure,
+		svc.ListScheduleVersions,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceDiffScheduleVersionsProcedure, connect.NewUnaryHandler(
+		ExtensionServiceDiffScheduleVersionsProcedure,
+		svc.DiffScheduleVersions,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceRollbackScheduleProcedure, connect.NewUnaryHandler(
+		ExtensionServiceRollbackScheduleProcedure,
+		svc.RollbackSchedule,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceCreateDraftProcedure, connect.NewUnaryHandler(
+		ExtensionServiceCreateDraftProcedure,
+		svc.CreateDraft,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceGetDraftProcedure, connect.NewUnaryHandler(
+		ExtensionServiceGetDraftProcedure,
+		svc.GetDraft,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceListDraftsProcedure, connect.NewUnaryHandler(
+		ExtensionServiceListDraftsProcedure,
+		svc.ListDrafts,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceUpsertDraftOfficeHourProcedure, connect.NewUnaryHandler(
+		ExtensionServiceUpsertDraftOfficeHourProcedure,
+		svc.UpsertDraftOfficeHour,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceDeleteDraftOfficeHourProcedure, connect.NewUnaryHandler(
+		ExtensionServiceDeleteDraftOfficeHourProcedure,
+		svc.DeleteDraftOfficeHour,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServicePublishDraftProcedure, connect.NewUnaryHandler(
+		ExtensionServicePublishDraftProcedure,
+		svc.PublishDraft,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceDeleteDraftProcedure, connect.NewUnaryHandler(
+		ExtensionServiceDeleteDraftProcedure,
+		svc.DeleteDraft,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceExportScheduleProcedure, connect.NewUnaryHandler(
+		ExtensionServiceExportScheduleProcedure,
+		svc.ExportSchedule,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceImportScheduleProcedure, connect.NewUnaryHandler(
+		ExtensionServiceImportScheduleProcedure,
+		svc.ImportSchedule,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceListDeletedOfficeHoursProcedure, connect.NewUnaryHandler(
+		ExtensionServiceListDeletedOfficeHoursProcedure,
+		svc.ListDeletedOfficeHours,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceRestoreOfficeHourProcedure, connect.NewUnaryHandler(
+		ExtensionServiceRestoreOfficeHourProcedure,
+		svc.RestoreOfficeHour,
+		opts...,
+	))
+
+	mux.Handle(ExtensionServiceInvalidateHolidayCacheProcedure, connect.NewUnaryHandler(
+		ExtensionServiceInvalidateHolidayCacheProcedure,
+		svc.InvalidateHolidayCache,
+		opts...,
+	))
+
+	return "/" + ExtensionServiceName + "/", mux
+}
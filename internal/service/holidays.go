@@ -0,0 +1,19 @@
+package service
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+)
+
+// InvalidateHolidayCache drops all cached public holidays so changes of the
+// holiday calendar are picked up immediately instead of after the cache TTL.
+// Note that only the cache of the replica serving the request is dropped.
+func (svc *Service) InvalidateHolidayCache(ctx context.Context, req *connect.Request[InvalidateHolidayCacheRequest]) (*connect.Response[InvalidateHolidayCacheResponse], error) {
+	svc.providers.Resolver.InvalidateHolidayCache()
+
+	// the open state may have changed with the holidays
+	svc.providers.Watcher.Trigger()
+
+	return connect.NewResponse(&InvalidateHolidayCacheResponse{}), nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+func (svc *Service) SetOverride(ctx context.Context, req *connect.Request[SetOverrideRequest]) (*connect.Response[repo.Override], error) {
+	now := time.Now()
+
+	if req.Msg.Until != nil && !req.Msg.Until.After(now) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("until must be in the future"))
+	}
+
+	if !req.Msg.Mode.IsValid() {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid opening mode %q", req.Msg.Mode))
+	}
+
+	switch {
+	case !req.Msg.Open && req.Msg.Mode != "" && req.Msg.Mode != repo.ModePhoneOnly:
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("only mode %q is allowed when forcing a location closed", repo.ModePhoneOnly))
+
+	case req.Msg.Open && req.Msg.Mode == repo.ModePhoneOnly:
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("mode %q is not allowed when forcing a location open", repo.ModePhoneOnly))
+	}
+
+	override := &repo.Override{
+		Location:  req.Msg.Location,
+		Open:      req.Msg.Open,
+		Mode:      req.Msg.Mode,
+		Until:     req.Msg.Until,
+		CreatedAt: now,
+	}
+
+	if err := svc.repo.SetOverride(ctx, override); err != nil {
+		return nil, err
+	}
+
+	svc.publishOverride(ctx, override.Location)
+
+	return connect.NewResponse(override), nil
+}
+
+func (svc *Service) GetOverride(ctx context.Context, req *connect.Request[GetOverrideRequest]) (*connect.Response[GetOverrideResponse], error) {
+	override, err := svc.repo.GetOverride(ctx, req.Msg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	// only report overrides that are still active
+	if override != nil && !override.ActiveAt(time.Now()) {
+		override = nil
+	}
+
+	return connect.NewResponse(&GetOverrideResponse{
+		Override: override,
+	}), nil
+}
+
+func (svc *Service) RevokeOverride(ctx context.Context, req *connect.Request[RevokeOverrideRequest]) (*connect.Response[RevokeOverrideResponse], error) {
+	if err := svc.repo.RevokeOverride(ctx, req.Msg.Location, time.Now()); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		}
+
+		return nil, err
+	}
+
+	svc.publishOverride(ctx, req.Msg.Location)
+
+	return connect.NewResponse(&RevokeOverrideResponse{}), nil
+}
+
+// publishOverride immediately publishes the open state of location after an
+// override has been changed.
+func (svc *Service) publishOverride(ctx context.Context, location string) {
+	if err := svc.providers.Watcher.PublishState(ctx, location); err != nil {
+		slog.Error("failed to publish open state events", "location", location, "error", err)
+	}
+
+	svc.providers.Watcher.Trigger()
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// etag returns the value of the ETag header for revision.
+func etag(revision int64) string {
+	return strconv.Quote(strconv.FormatInt(revision, 10))
+}
+
+// ifMatchRevision returns the revision expected by the If-Match header of h.
+// Zero is returned if the header is unset and repo.AnyRevision if it matches
+// any existing revision.
+func ifMatchRevision(h http.Header) (int64, error) {
+	value := strings.TrimSpace(h.Get(IfMatchHeader))
+	switch value {
+	case "":
+		return 0, nil
+	case "*":
+		return repo.AnyRevision, nil
+	}
+
+	revision, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid %s header %q", IfMatchHeader, value))
+	}
+
+	return revision, nil
+}
+
+// upsertError converts errors returned by UpsertOfficeHours and other
+// changes of the live schedule to connect errors.
+func upsertError(err error) error {
+	if errors.Is(err, repo.ErrRevisionMismatch) {
+		return connect.NewError(connect.CodeAborted, err)
+	}
+
+	// If-Match: * has been used for an office hour that does not exist
+	if errors.Is(err, repo.ErrNotFound) {
+		return connect.NewError(connect.CodeNotFound, err)
+	}
+
+	// the resulting live schedule would contain conflicting office hours
+	var verr *repo.ValidationError
+	if errors.As(err, &verr) {
+		return validationError(connect.CodeFailedPrecondition, err)
+	}
+
+	return err
+}
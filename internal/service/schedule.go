@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+func (svc *Service) ExportSchedule(ctx context.Context, req *connect.Request[ExportScheduleRequest]) (*connect.Response[ExportScheduleResponse], error) {
+	doc, err := svc.repo.ExportSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	format := req.Msg.Format
+	if format == "" {
+		format = repo.FormatYAML
+	}
+
+	blob, err := doc.Encode(format)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return connect.NewResponse(&ExportScheduleResponse{
+		Format:   format,
+		Document: string(blob),
+	}), nil
+}
+
+func (svc *Service) ImportSchedule(ctx context.Context, req *connect.Request[ImportScheduleRequest]) (*connect.Response[ImportScheduleResponse], error) {
+	doc, err := repo.DecodeScheduleDocument([]byte(req.Msg.Document), req.Msg.Format)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	if err := doc.Validate(); err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	diff, version, err := svc.repo.ImportSchedule(ctx, doc, req.Msg.DryRun)
+	if err != nil {
+		return nil, upsertError(err)
+	}
+
+	if version != nil {
+		defer svc.providers.Watcher.Trigger()
+	}
+
+	return connect.NewResponse(&ImportScheduleResponse{
+		Diff:    diff,
+		Version: version,
+	}), nil
+}
@@ -3,7 +3,8 @@ package service
 import (
 	"context"
 	"errors"
-	"log/slog"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/bufbuild/connect-go"
@@ -12,43 +13,212 @@ import (
 	"github.com/tierklinik-dobersberg/apis/gen/go/tkd/office_hours/v1/office_hoursv1connect"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/config"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// LocationHeader may be set on any request to scope the RPC to a specific
+// location. If unset, requests operate on the default location (or on all
+// locations for ListHours).
+const LocationHeader = "X-Office-Hours-Location"
+
+// ModeHeader is set on IsOpen responses to the opening mode that applies if
+// the location is open or to "phone-only" if the location is closed but can
+// be reached by phone.
+const ModeHeader = "X-Office-Hours-Mode"
+
+// DraftHeader may be set on IsOpen and OfficeHourRanges requests to preview
+// the office hours of a draft instead of the live schedule.
+const DraftHeader = "X-Office-Hours-Draft"
+
+// OverrideHeader is set on IsOpen responses if the open state is forced by a
+// manual override.
+const OverrideHeader = "X-Office-Hours-Override"
+
+// ETagHeader is set on upsert responses to the revision of the office hour.
+// The revision may be passed in the IfMatchHeader of the next upsert to
+// detect concurrent modifications.
+const (
+	ETagHeader    = "ETag"
+	IfMatchHeader = "If-Match"
+)
+
+// RevisionHeader is set on ListHours responses once per office hour to
+// "<name>=<revision>" since the OfficeHour message cannot carry the revision.
+const RevisionHeader = "X-Office-Hours-Revision"
+
+// DateRangeHeader is set on ListHours responses once per office hour with a
+// date range to "<name>=<from>/<to>" (with a "/yearly" suffix for yearly
+// ranges). The OfficeHour message only carries the first day of the range.
+// Weekday restrictions of date ranges are only returned by the
+// ListExtendedOfficeHours RPC.
+const DateRangeHeader = "X-Office-Hours-Date-Range"
+
 type Service struct {
 	office_hoursv1connect.UnimplementedOfficeHourServiceHandler
 
-	repo      *repo.Repo
+	repo      repo.OfficeHourStore
 	providers *config.Providers
 }
 
 func New(providers *config.Providers) *Service {
 	return &Service{
-		repo:      providers.Repo,
+		repo:      providers.Store,
 		providers: providers,
 	}
 }
 
 func (svc *Service) ListHours(ctx context.Context, req *connect.Request[v1.ListHoursRequest]) (*connect.Response[v1.ListHoursResponse], error) {
-	hours, err := svc.repo.ListOfficeHours(ctx)
+	hours, err := svc.repo.ListOfficeHours(ctx, req.Header().Get(LocationHeader))
 	if err != nil {
 		return nil, err
 	}
 
-	return connect.NewResponse(&v1.ListHoursResponse{
+	res := &v1.ListHoursResponse{
+		OfficeHours: make([]*v1.OfficeHour, len(hours)),
+	}
+
+	response := connect.NewResponse(res)
+
+	for idx, h := range hours {
+		res.OfficeHours[idx] = h.ToProto()
+
+		response.Header().Add(RevisionHeader, fmt.Sprintf("%s=%d", h.ID.Hex(), h.Revision))
+
+		if dr := h.DateRange; dr != nil {
+			value := fmt.Sprintf("%s=%s/%s", h.ID.Hex(), dr.From, dr.To)
+			if dr.Yearly {
+				value += "/yearly"
+			}
+
+			response.Header().Add(DateRangeHeader, value)
+		}
+	}
+
+	return response, nil
+}
+
+func (svc *Service) UpsertOfficeHour(ctx context.Context, req *connect.Request[v1.OfficeHour]) (*connect.Response[v1.OfficeHour], error) {
+	model, err := repo.ModelFromProto(req.Msg)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	// Keep all fields that cannot be represented by the protobuf message
+	// when updating an existing office hour.
+	if req.Msg.Name != "" {
+		existing, err := svc.existingOfficeHour(ctx, req.Msg.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		if existing != nil {
+			model.CopyExtendedFields(existing)
+		}
+	}
+
+	if len(req.Header().Values(LocationHeader)) > 0 {
+		model.Location = req.Header().Get(LocationHeader)
+	}
+
+	model.Revision, err = ifMatchRevision(req.Header())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := svc.validateLive(ctx, model); err != nil {
+		return nil, err
+	}
+
+	hour, err := svc.repo.UpsertOfficeHours(ctx, model)
+	if err != nil {
+		return nil, upsertError(err)
+	}
+
+	defer svc.providers.Watcher.Trigger()
+
+	res := connect.NewResponse(hour.ToProto())
+	res.Header().Set(ETagHeader, etag(hour.Revision))
+
+	return res, nil
+}
+
+func (svc *Service) ListExtendedOfficeHours(ctx context.Context, req *connect.Request[ListExtendedOfficeHoursRequest]) (*connect.Response[ListExtendedOfficeHoursResponse], error) {
+	hours, err := svc.repo.ListOfficeHours(ctx, req.Msg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&ListExtendedOfficeHoursResponse{
 		OfficeHours: hours,
 	}), nil
 }
 
-func (svc *Service) UpsertOfficeHour(ctx context.Context, req *connect.Request[v1.OfficeHour]) (*connect.Response[v1.OfficeHour], error) {
-	hour, err := svc.repo.UpsertOfficeHours(ctx, req.Msg)
+func (svc *Service) UpsertExtendedOfficeHour(ctx context.Context, req *connect.Request[repo.OfficeHourModel]) (*connect.Response[repo.OfficeHourModel], error) {
+	// The If-Match header takes precedence over the revision in the
+	// message.
+	revision, err := ifMatchRevision(req.Header())
 	if err != nil {
 		return nil, err
 	}
 
+	if revision != 0 {
+		req.Msg.Revision = revision
+	}
+
+	if err := svc.validateLive(ctx, req.Msg); err != nil {
+		return nil, err
+	}
+
+	hour, err := svc.repo.UpsertOfficeHours(ctx, req.Msg)
+	if err != nil {
+		return nil, upsertError(err)
+	}
+
 	defer svc.providers.Watcher.Trigger()
 
-	return connect.NewResponse(hour), nil
+	res := connect.NewResponse(hour)
+	res.Header().Set(ETagHeader, etag(hour.Revision))
+
+	return res, nil
+}
+
+// existingOfficeHour returns the office hour name including deleted ones,
+// since upserting a deleted office hour restores it, or nil if it does not
+// exist.
+func (svc *Service) existingOfficeHour(ctx context.Context, name string) (*repo.OfficeHourModel, error) {
+	existing, err := svc.repo.GetOfficeHour(ctx, name)
+	if err == nil {
+		return existing, nil
+	}
+
+	if !errors.Is(err, repo.ErrNotFound) {
+		return nil, err
+	}
+
+	deleted, err := svc.repo.ListDeletedOfficeHours(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for idx := range deleted {
+		if deleted[idx].ID.Hex() == name {
+			return &deleted[idx], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// validateLive validates model against all office hours of the live
+// schedule.
+func (svc *Service) validateLive(ctx context.Context, model *repo.OfficeHourModel) error {
+	others, err := svc.repo.ListOfficeHours(ctx, model.Location)
+	if err != nil {
+		return err
+	}
+
+	return validateOfficeHour(model, others)
 }
 
 func (svc *Service) DeleteOfficeHour(ctx context.Context, req *connect.Request[v1.DeleteOfficeHourRequest]) (*connect.Response[emptypb.Empty], error) {
@@ -66,65 +236,290 @@ func (svc *Service) DeleteOfficeHour(ctx context.Context, req *connect.Request[v
 }
 
 func (svc *Service) OfficeHourRanges(ctx context.Context, req *connect.Request[v1.OfficeHourRangesRequest]) (*connect.Response[v1.OfficeHourRangesResponse], error) {
-	t := time.Now().Local()
+	t := time.Now().In(svc.providers.TimeZone)
 
 	if req.Msg.Date != nil {
-		t = req.Msg.Date.AsTimeInLocation(time.Local)
+		t = req.Msg.Date.AsTimeInLocation(svc.providers.TimeZone)
 	}
 
-	hours, err := svc.providers.Resolver.ResolveOfficeHours(ctx, t)
+	location := req.Header().Get(LocationHeader)
+
+	r, err := svc.resolverFor(ctx, req.Header().Get(DraftHeader))
 	if err != nil {
 		return nil, err
 	}
-	if len(hours) == 0 {
-		return connect.NewResponse(new(v1.OfficeHourRangesResponse)), nil
+
+	// the office hours of the previous day might span midnight
+	days, err := r.ResolveOfficeHoursBetween(ctx, t.AddDate(0, 0, -1), t, location)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(hours) > 1 {
-		slog.Warn("found multiple office hours, only considering the first one", "time", t.Format(time.RFC3339))
+	override, err := svc.repo.GetOverride(ctx, location)
+	if err != nil {
+		return nil, err
 	}
 
-	res := &v1.OfficeHourRangesResponse{
-		OfficeHour: hours[0],
-		OpenRanges: make([]*commonv1.TimeRange, len(hours[0].TimeRanges)),
+	res := new(v1.OfficeHourRangesResponse)
+
+	hour, ranges := openRanges(days[0].OfficeHours, days[1].OfficeHours, days[1].Date, override)
+	if hour != nil {
+		res.OfficeHour = hour.ToProto()
 	}
 
-	for idx, tr := range hours[0].TimeRanges {
-		res.OpenRanges[idx] = tr.At(t)
+	for _, tr := range ranges {
+		res.OpenRanges = append(res.OpenRanges, commonv1.NewTimeRange(tr.From, tr.To))
 	}
 
 	return connect.NewResponse(res), nil
 }
 
-func (svc *Service) IsOpen(ctx context.Context, req *connect.Request[v1.IsOpenRequest]) (*connect.Response[v1.IsOpenResponse], error) {
+// maxOpeningRangesDays is the maximum number of days that may be queried
+// using the OpeningRanges RPC.
+const maxOpeningRangesDays = 366
+
+func (svc *Service) OpeningRanges(ctx context.Context, req *connect.Request[OpeningRangesRequest]) (*connect.Response[OpeningRangesResponse], error) {
+	from, err := time.ParseInLocation("2006-01-02", req.Msg.From, svc.providers.TimeZone)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid from date: %w", err))
+	}
+
+	to, err := time.ParseInLocation("2006-01-02", req.Msg.To, svc.providers.TimeZone)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid to date: %w", err))
+	}
+
+	if to.Before(from) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("to must not be before from"))
+	}
+
+	if to.Sub(from) >= maxOpeningRangesDays*24*time.Hour {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("calendar window must not exceed %d days", maxOpeningRangesDays))
+	}
+
+	// the office hours of the day before from might span midnight
+	days, err := svc.providers.Resolver.ResolveOfficeHoursBetween(ctx, from.AddDate(0, 0, -1), to, req.Msg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	override, err := svc.repo.GetOverride(ctx, req.Msg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &OpeningRangesResponse{
+		Days: make([]DayOpeningRanges, len(days)-1),
+	}
+
+	for idx, day := range days[1:] {
+		hour, ranges := openRanges(days[idx].OfficeHours, day.OfficeHours, day.Date, override)
+
+		res.Days[idx] = DayOpeningRanges{
+			Date:       day.Date.Format("2006-01-02"),
+			OpenRanges: make([]TimeRange, len(ranges)),
+		}
+
+		if hour != nil {
+			res.Days[idx].OfficeHour = hour.ID.Hex()
+		}
+
+		for rIdx, tr := range ranges {
+			res.Days[idx].OpenRanges[rIdx] = TimeRange{
+				From: tr.From,
+				To:   tr.To,
+			}
+		}
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+func (svc *Service) NextChange(ctx context.Context, req *connect.Request[NextChangeRequest]) (*connect.Response[NextChangeResponse], error) {
 	t := time.Now()
 
-	if req.Msg.Timestamp.IsValid() {
-		t = req.Msg.Timestamp.AsTime()
+	if req.Msg.Timestamp != nil {
+		t = *req.Msg.Timestamp
 	}
 
-	// switch t to local time
-	t = t.Local()
+	// switch t to the configured timezone
+	t = t.In(svc.providers.TimeZone)
 
-	hours, err := svc.providers.Resolver.ResolveOfficeHours(ctx, t)
+	state, err := svc.providers.Resolver.ResolveOpenState(ctx, t, req.Msg.Location, svc.providers.NextChangeHorizon)
 	if err != nil {
 		return nil, err
 	}
 
-	isOpen := false
-	var appliedHours *v1.OfficeHour
+	res := &NextChangeResponse{
+		Open: state.Open,
+		Mode: state.Mode,
+	}
+
+	if !state.NextOpen.IsZero() {
+		res.NextOpen = &state.NextOpen
+	}
+
+	if !state.NextClose.IsZero() {
+		res.NextClose = &state.NextClose
+	}
+
+	return connect.NewResponse(res), nil
+}
+
+// resolverFor returns the resolver for the draft identified by draftID or the
+// live resolver if draftID is empty.
+func (svc *Service) resolverFor(ctx context.Context, draftID string) (*resolver.Resolver, error) {
+	if draftID == "" {
+		return svc.providers.Resolver, nil
+	}
+
+	draft, err := svc.repo.GetDraft(ctx, draftID)
+	if err != nil {
+		if errors.Is(err, repo.ErrDraftNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		}
+
+		return nil, err
+	}
+
+	return svc.providers.Resolver.WithSource(draft.Schedule()), nil
+}
+
+// openRanges returns the office hour that applies at the day of t and the
+// time ranges at which it is considered open. hours are the office hours
+// selected by the resolver. If multiple office hours apply, the open ranges
+// of all of them are merged and the first one is returned, like
+// ResolveOpenState does. Time ranges of the previous day that span midnight
+// are included up to their end. If override is set, it is applied to the
+// returned time ranges.
+func openRanges(previous, hours []repo.OfficeHourModel, t time.Time, override *repo.Override) (*repo.OfficeHourModel, []repo.TimeRange) {
+	var (
+		hour   *repo.OfficeHourModel
+		ranges []repo.TimeRange
+	)
+
+	year, month, day := t.Date()
+	dayStart := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+
+	for _, h := range previous {
+		for _, tr := range h.TimeRanges {
+			if tr.OpeningMode() == repo.ModePhoneOnly {
+				continue
+			}
+
+			// only the part after midnight belongs to this day
+			if _, to := tr.At(dayStart.AddDate(0, 0, -1)); to.After(dayStart) {
+				ranges = append(ranges, repo.TimeRange{
+					From: dayStart,
+					To:   to,
+				})
+			}
+		}
+	}
+
+	if len(hours) > 0 {
+		hour = &hours[0]
+	}
+
 	for _, h := range hours {
 		for _, tr := range h.TimeRanges {
-			if tr.At(t).Includes(t) {
-				isOpen = true
-				appliedHours = h
-				break
+			// phone-only time ranges do not open a location
+			if tr.OpeningMode() == repo.ModePhoneOnly {
+				continue
 			}
+
+			from, to := tr.At(dayStart)
+
+			ranges = append(ranges, repo.TimeRange{
+				From: from,
+				To:   to,
+			})
 		}
 	}
 
-	return connect.NewResponse(&v1.IsOpenResponse{
-		Open:       isOpen,
-		OfficeHour: appliedHours,
+	ranges = mergeRanges(ranges)
+
+	if override != nil {
+		ranges = override.Apply(ranges, dayStart, dayStart.AddDate(0, 0, 1))
+	}
+
+	return hour, ranges
+}
+
+// mergeRanges sorts ranges by their start time and merges overlapping
+// ranges. Adjacent ranges are kept since they usually differ in their
+// opening mode.
+func mergeRanges(ranges []repo.TimeRange) []repo.TimeRange {
+	slices.SortFunc(ranges, func(a, b repo.TimeRange) int {
+		return a.From.Compare(b.From)
+	})
+
+	var merged []repo.TimeRange
+	for _, tr := range ranges {
+		if last := len(merged) - 1; last >= 0 && tr.From.Before(merged[last].To) {
+			if tr.To.After(merged[last].To) {
+				merged[last].To = tr.To
+			}
+
+			continue
+		}
+
+		merged = append(merged, tr)
+	}
+
+	return merged
+}
+
+func (svc *Service) IsOpen(ctx context.Context, req *connect.Request[v1.IsOpenRequest]) (*connect.Response[v1.IsOpenResponse], error) {
+	t := time.Now()
+
+	if req.Msg.Timestamp.IsValid() {
+		t = req.Msg.Timestamp.AsTime()
+	}
+
+	// switch t to the configured timezone
+	t = t.In(svc.providers.TimeZone)
+
+	r, err := svc.resolverFor(ctx, req.Header().Get(DraftHeader))
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := r.ResolveOpenState(ctx, t, req.Header().Get(LocationHeader), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	res := connect.NewResponse(&v1.IsOpenResponse{
+		Open: state.Open,
+	})
+
+	if state.OfficeHour != nil {
+		res.Msg.OfficeHour = state.OfficeHour.ToProto()
+	}
+
+	if state.Mode != "" {
+		res.Header().Set(ModeHeader, string(state.Mode))
+	}
+
+	if state.Override != nil {
+		res.Header().Set(OverrideHeader, "true")
+	}
+
+	return res, nil
+}
+
+func (svc *Service) ListAuditEntries(ctx context.Context, req *connect.Request[ListAuditEntriesRequest]) (*connect.Response[ListAuditEntriesResponse], error) {
+	if req.Msg.OfficeHour == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("missing officeHour"))
+	}
+
+	entries, err := svc.repo.ListAuditEntries(ctx, req.Msg.OfficeHour)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&ListAuditEntriesResponse{
+		Entries: entries,
 	}), nil
 }
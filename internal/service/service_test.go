@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	commonv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/common/v1"
+	v1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/office_hours/v1"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/config"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/watcher"
+)
+
+type noHolidays struct{}
+
+func (noHolidays) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	return nil, nil
+}
+
+// newTestService returns a service for store that interprets office hours
+// in UTC. The watcher is not started.
+func newTestService(store repo.OfficeHourStore) *Service {
+	r := resolver.NewResolver(store, noHolidays{}, 0, time.UTC)
+
+	return New(&config.Providers{
+		Store:    store,
+		Resolver: r,
+		Watcher:  watcher.New(store, r, nil, time.UTC, watcher.LeadTimes{}),
+		TimeZone: time.UTC,
+	})
+}
+
+func TestUpsertDeletedOfficeHourKeepsExtendedFields(t *testing.T) {
+	ctx := context.Background()
+	store := repo.NewMemoryStore()
+	svc := newTestService(store)
+
+	hour, err := store.UpsertOfficeHours(ctx, &repo.OfficeHourModel{
+		Location:   "surgery",
+		DateRange:  &repo.DateRange{From: "07-15", To: "08-15", Yearly: true},
+		ValidFrom:  "2024-01-01",
+		TimeRanges: []repo.DayTimeRange{{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}, Mode: repo.ModeEmergencyOnly}},
+	})
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	if err := store.DeleteOfficeHour(ctx, hour.ID.Hex()); err != nil {
+		t.Fatalf("failed to delete office hour: %s", err)
+	}
+
+	// upserting the deleted office hour using the protobuf API restores it
+	if _, err := svc.UpsertOfficeHour(ctx, connect.NewRequest(hour.ToProto())); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	restored, err := store.GetOfficeHour(ctx, hour.ID.Hex())
+	if err != nil {
+		t.Fatalf("expected the office hour to be restored: %s", err)
+	}
+
+	if restored.Location != "surgery" || restored.DateRange == nil || restored.ValidFrom != "2024-01-01" || restored.TimeRanges[0].Mode != repo.ModeEmergencyOnly {
+		t.Errorf("expected the extended fields to be kept, got %+v", restored)
+	}
+}
+
+func TestOpenRangesAfterMidnight(t *testing.T) {
+	ctx := context.Background()
+	store := repo.NewMemoryStore()
+	svc := newTestService(store)
+
+	if _, err := store.UpsertOfficeHours(ctx, &repo.OfficeHourModel{
+		DayOfWeek:  time.Friday,
+		TimeRanges: []repo.DayTimeRange{{Start: repo.DayTime{Hours: 20}, End: repo.DayTime{Hours: 2}}},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	friday := time.Date(2024, time.June, 7, 0, 0, 0, 0, time.UTC)
+	saturday := friday.AddDate(0, 0, 1)
+
+	res, err := svc.OpeningRanges(ctx, connect.NewRequest(&OpeningRangesRequest{
+		From: "2024-06-07",
+		To:   "2024-06-08",
+	}))
+	if err != nil {
+		t.Fatalf("failed to resolve opening ranges: %s", err)
+	}
+
+	days := res.Msg.Days
+	if len(days) != 2 {
+		t.Fatalf("expected two days, got %d", len(days))
+	}
+
+	if len(days[0].OpenRanges) != 1 || !days[0].OpenRanges[0].From.Equal(friday.Add(20*time.Hour)) || !days[0].OpenRanges[0].To.Equal(saturday.Add(2*time.Hour)) {
+		t.Errorf("expected friday to be open from 20:00 to 02:00, got %+v", days[0].OpenRanges)
+	}
+
+	// saturday is open until the friday office hour ends
+	if days[1].OfficeHour != "" || len(days[1].OpenRanges) != 1 || !days[1].OpenRanges[0].From.Equal(saturday) || !days[1].OpenRanges[0].To.Equal(saturday.Add(2*time.Hour)) {
+		t.Errorf("expected saturday to be open until 02:00, got %+v", days[1])
+	}
+
+	ranges, err := svc.OfficeHourRanges(ctx, connect.NewRequest(&v1.OfficeHourRangesRequest{
+		Date: commonv1.FromTime(saturday),
+	}))
+	if err != nil {
+		t.Fatalf("failed to resolve office hour ranges: %s", err)
+	}
+
+	if len(ranges.Msg.OpenRanges) != 1 || !ranges.Msg.OpenRanges[0].From.AsTime().Equal(saturday) || !ranges.Msg.OpenRanges[0].To.AsTime().Equal(saturday.Add(2*time.Hour)) {
+		t.Errorf("expected saturday to be open until 02:00, got %v", ranges.Msg.OpenRanges)
+	}
+
+	// the location is open at the same time
+	state, err := svc.providers.Resolver.ResolveOpenState(ctx, saturday.Add(time.Hour), "", 0)
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if !state.Open {
+		t.Errorf("expected the location to be open on saturday at 01:00")
+	}
+}
+
+func TestOpenRangesMultipleOfficeHours(t *testing.T) {
+	ctx := context.Background()
+	store := repo.NewMemoryStore()
+	svc := newTestService(store)
+
+	for _, tr := range []repo.DayTimeRange{
+		{Start: repo.DayTime{Hours: 14}, End: repo.DayTime{Hours: 18}},
+		{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}},
+	} {
+		if _, err := store.UpsertOfficeHours(ctx, &repo.OfficeHourModel{
+			DayOfWeek:  time.Monday,
+			TimeRanges: []repo.DayTimeRange{tr},
+		}); err != nil {
+			t.Fatalf("failed to upsert office hour: %s", err)
+		}
+	}
+
+	monday := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	res, err := svc.OpeningRanges(ctx, connect.NewRequest(&OpeningRangesRequest{
+		From: "2024-06-03",
+		To:   "2024-06-03",
+	}))
+	if err != nil {
+		t.Fatalf("failed to resolve opening ranges: %s", err)
+	}
+
+	ranges := res.Msg.Days[0].OpenRanges
+	if len(ranges) != 2 || !ranges[0].From.Equal(monday.Add(8*time.Hour)) || !ranges[1].From.Equal(monday.Add(14*time.Hour)) {
+		t.Errorf("expected the ranges of both office hours, got %+v", ranges)
+	}
+}
+
+func TestUpsertIfMatchAny(t *testing.T) {
+	ctx := context.Background()
+	store := repo.NewMemoryStore()
+	svc := newTestService(store)
+
+	newHour := func() *repo.OfficeHourModel {
+		return &repo.OfficeHourModel{
+			DayOfWeek:  time.Monday,
+			TimeRanges: []repo.DayTimeRange{{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}}},
+		}
+	}
+
+	// the office hour does not exist yet
+	req := connect.NewRequest(newHour())
+	req.Header().Set(IfMatchHeader, "*")
+
+	if _, err := svc.UpsertExtendedOfficeHour(ctx, req); connect.CodeOf(err) != connect.CodeNotFound {
+		t.Fatalf("expected NotFound but got %v", err)
+	}
+
+	created, err := store.UpsertOfficeHours(ctx, newHour())
+	if err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	protoReq := connect.NewRequest(created.ToProto())
+	protoReq.Header().Set(IfMatchHeader, "*")
+
+	res, err := svc.UpsertOfficeHour(ctx, protoReq)
+	if err != nil {
+		t.Fatalf("expected the existing office hour to match: %s", err)
+	}
+
+	if res.Header().Get(ETagHeader) != etag(2) {
+		t.Errorf("expected revision 2, got %s", res.Header().Get(ETagHeader))
+	}
+}
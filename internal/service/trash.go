@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+func (svc *Service) ListDeletedOfficeHours(ctx context.Context, req *connect.Request[ListDeletedOfficeHoursRequest]) (*connect.Response[ListDeletedOfficeHoursResponse], error) {
+	hours, err := svc.repo.ListDeletedOfficeHours(ctx, req.Msg.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&ListDeletedOfficeHoursResponse{
+		OfficeHours: hours,
+	}), nil
+}
+
+func (svc *Service) RestoreOfficeHour(ctx context.Context, req *connect.Request[RestoreOfficeHourRequest]) (*connect.Response[repo.OfficeHourModel], error) {
+	if req.Msg.Name == "" {
+		return nil, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("missing name"))
+	}
+
+	// the store checks the office hour against the live schedule while
+	// restoring it.
+	hour, err := svc.repo.RestoreOfficeHour(ctx, req.Msg.Name)
+	if err != nil {
+		var verr *repo.ValidationError
+		switch {
+		case errors.Is(err, repo.ErrNotFound):
+			return nil, connect.NewError(connect.CodeNotFound, err)
+
+		case errors.As(err, &verr):
+			return nil, invalidArgument(err)
+		}
+
+		return nil, err
+	}
+
+	defer svc.providers.Watcher.Trigger()
+
+	res := connect.NewResponse(hour)
+	res.Header().Set(ETagHeader, etag(hour.Revision))
+
+	return res, nil
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"time"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// ListExtendedOfficeHoursRequest is the request message for the
+// ListExtendedOfficeHours RPC.
+type ListExtendedOfficeHoursRequest struct {
+	// Location may be set to only return office hours of that location.
+	Location string `json:"location,omitempty"`
+}
+
+// ListExtendedOfficeHoursResponse is the response message for the
+// ListExtendedOfficeHours RPC. In contrast to the OfficeHour protobuf
+// message, date ranges are returned with all their days and weekdays.
+type ListExtendedOfficeHoursResponse struct {
+	OfficeHours []repo.OfficeHourModel `json:"officeHours"`
+}
+
+// OpeningRangesRequest is the request message for the OpeningRanges RPC.
+type OpeningRangesRequest struct {
+	// From is the first day (YYYY-MM-DD) of the calendar window.
+	From string `json:"from"`
+
+	// To is the last day (YYYY-MM-DD) of the calendar window. The day itself
+	// is included in the response.
+	To string `json:"to"`
+
+	// Location may be set to query the opening hours of a specific location.
+	// If empty, the default location is used.
+	Location string `json:"location,omitempty"`
+}
+
+// OpeningRangesResponse is the response message for the OpeningRanges RPC.
+type OpeningRangesResponse struct {
+	// Days holds one entry for each day in the requested calendar window.
+	Days []DayOpeningRanges `json:"days"`
+}
+
+// DayOpeningRanges describes the opening hours at a single day.
+type DayOpeningRanges struct {
+	// Date is the day (YYYY-MM-DD) this entry describes.
+	Date string `json:"date"`
+
+	// OfficeHour is the name of the office hour that is valid at Date.
+	// It is empty if there are no office hours for Date.
+	OfficeHour string `json:"officeHour,omitempty"`
+
+	// OpenRanges is a list of time-ranges considered "open" at Date.
+	OpenRanges []TimeRange `json:"openRanges"`
+}
+
+// TimeRange is a time range with an absolute start and end time.
+type TimeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// NextChangeRequest is the request message for the NextChange RPC.
+type NextChangeRequest struct {
+	// Timestamp specifies the time from which to search for the next
+	// opening and closing. If unset, the current time is used.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+
+	// Location may be set to query a specific location. If empty, the
+	// default location is used.
+	Location string `json:"location,omitempty"`
+}
+
+// NextChangeResponse is the response message for the NextChange RPC.
+type NextChangeResponse struct {
+	// Open indicates whether the location is open at the requested
+	// timestamp.
+	Open bool `json:"open"`
+
+	// Mode is the opening mode that applies if the location is open or
+	// "phone-only" if it is closed but can be reached by phone.
+	Mode repo.OpeningMode `json:"mode,omitempty"`
+
+	// NextOpen is the next time the location opens. It is unset if the
+	// location does not open within the configured horizon.
+	NextOpen *time.Time `json:"nextOpen,omitempty"`
+
+	// NextClose is the next time the location closes. It is unset if the
+	// location does not close within the configured horizon.
+	NextClose *time.Time `json:"nextClose,omitempty"`
+}
+
+// WatchOpenStateRequest is the request message for the WatchOpenState RPC.
+type WatchOpenStateRequest struct {
+	// Location may be set to watch a specific location. If empty, the
+	// default location is watched.
+	Location string `json:"location,omitempty"`
+}
+
+// WatchOpenStateResponse is sent by the WatchOpenState RPC whenever the open
+// state of the watched location changes.
+type WatchOpenStateResponse struct {
+	// Open indicates whether the location is currently open.
+	Open bool `json:"open"`
+
+	// Mode is the opening mode that applies if the location is open or
+	// "phone-only" if it is closed but can be reached by phone.
+	Mode repo.OpeningMode `json:"mode,omitempty"`
+
+	// OfficeHour is the name of the office hour that applies. It is empty
+	// if no office hour applies.
+	OfficeHour string `json:"officeHour,omitempty"`
+
+	// NextChange is the time at which the open state or the opening mode is
+	// expected to change next. It is unset if no change is expected within
+	// the next day.
+	NextChange *time.Time `json:"nextChange,omitempty"`
+}
+
+// SetOverrideRequest is the request message for the SetOverride RPC.
+type SetOverrideRequest struct {
+	// Location is the location to override. If empty, the default location
+	// is overridden.
+	Location string `json:"location,omitempty"`
+
+	// Open is true to force the location open and false to force it closed.
+	Open bool `json:"open"`
+
+	// Mode is the opening mode while the location is forced open. If the
+	// location is forced closed, only "phone-only" is allowed.
+	Mode repo.OpeningMode `json:"mode,omitempty"`
+
+	// Until may be set to let the override expire. If unset, the override
+	// is active until it is revoked.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// GetOverrideRequest is the request message for the GetOverride RPC.
+type GetOverrideRequest struct {
+	Location string `json:"location,omitempty"`
+}
+
+// GetOverrideResponse is the response message for the GetOverride RPC.
+type GetOverrideResponse struct {
+	// Override is the active override or nil if there is none.
+	Override *repo.Override `json:"override,omitempty"`
+}
+
+// RevokeOverrideRequest is the request message for the RevokeOverride RPC.
+type RevokeOverrideRequest struct {
+	Location string `json:"location,omitempty"`
+}
+
+// RevokeOverrideResponse is the response message for the RevokeOverride RPC.
+type RevokeOverrideResponse struct{}
+
+// ListAuditEntriesRequest is the request message for the ListAuditEntries
+// RPC.
+type ListAuditEntriesRequest struct {
+	// OfficeHour is the name of the office hour.
+	OfficeHour string `json:"officeHour"`
+}
+
+// ListAuditEntriesResponse is the response message for the ListAuditEntries
+// RPC.
+type ListAuditEntriesResponse struct {
+	// Entries holds the audit trail, most recent entries first.
+	Entries []repo.AuditEntry `json:"entries"`
+}
+
+// ListScheduleVersionsRequest is the request message for the
+// ListScheduleVersions RPC.
+type ListScheduleVersionsRequest struct{}
+
+// ListScheduleVersionsResponse is the response message for the
+// ListScheduleVersions RPC.
+type ListScheduleVersionsResponse struct {
+	// Versions holds all schedule versions, most recent versions first.
+	// Office hours are not included.
+	Versions []repo.ScheduleVersion `json:"versions"`
+}
+
+// DiffScheduleVersionsRequest is the request message for the
+// DiffScheduleVersions RPC.
+type DiffScheduleVersionsRequest struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// RollbackScheduleRequest is the request message for the RollbackSchedule
+// RPC.
+type RollbackScheduleRequest struct {
+	// Version is the schedule version to restore.
+	Version int64 `json:"version"`
+}
+
+// CreateDraftRequest is the request message for the CreateDraft RPC.
+type CreateDraftRequest struct {
+	Description string `json:"description,omitempty"`
+}
+
+// GetDraftRequest is the request message for the GetDraft RPC.
+type GetDraftRequest struct {
+	ID string `json:"id"`
+}
+
+// ListDraftsRequest is the request message for the ListDrafts RPC.
+type ListDraftsRequest struct{}
+
+// ListDraftsResponse is the response message for the ListDrafts RPC.
+type ListDraftsResponse struct {
+	// Drafts holds all drafts without their office hours.
+	Drafts []repo.Draft `json:"drafts"`
+}
+
+// UpsertDraftOfficeHourRequest is the request message for the
+// UpsertDraftOfficeHour RPC.
+type UpsertDraftOfficeHourRequest struct {
+	// Draft is the ID of the draft.
+	Draft string `json:"draft"`
+
+	// OfficeHour is the office hour to create or replace. If it does not
+	// have a name yet, a new one is assigned.
+	OfficeHour repo.OfficeHourModel `json:"officeHour"`
+}
+
+// DeleteDraftOfficeHourRequest is the request message for the
+// DeleteDraftOfficeHour RPC.
+type DeleteDraftOfficeHourRequest struct {
+	Draft      string `json:"draft"`
+	OfficeHour string `json:"officeHour"`
+}
+
+// PublishDraftRequest is the request message for the PublishDraft RPC.
+type PublishDraftRequest struct {
+	ID string `json:"id"`
+
+	// At may be set to publish the draft at a later time. If unset, the
+	// draft is published immediately.
+	At *time.Time `json:"at,omitempty"`
+}
+
+// PublishDraftResponse is the response message for the PublishDraft RPC.
+type PublishDraftResponse struct {
+	// Version is the schedule version created by publishing the draft. It
+	// is unset if the draft has been scheduled for publishing.
+	Version *repo.ScheduleVersion `json:"version,omitempty"`
+
+	// Draft is the draft that has been published or scheduled.
+	Draft *repo.Draft `json:"draft,omitempty"`
+}
+
+// DeleteDraftRequest is the request message for the DeleteDraft RPC.
+type DeleteDraftRequest struct {
+	ID string `json:"id"`
+}
+
+// DeleteDraftResponse is the response message for the DeleteDraft RPC.
+type DeleteDraftResponse struct{}
+
+// ExportScheduleRequest is the request message for the ExportSchedule RPC.
+type ExportScheduleRequest struct {
+	// Format is the format of the document, either "yaml" (the default) or
+	// "json".
+	Format string `json:"format,omitempty"`
+}
+
+// ExportScheduleResponse is the response message for the ExportSchedule RPC.
+type ExportScheduleResponse struct {
+	Format string `json:"format"`
+
+	// Document is the encoded schedule document.
+	Document string `json:"document"`
+}
+
+// ImportScheduleRequest is the request message for the ImportSchedule RPC.
+type ImportScheduleRequest struct {
+	// Format is the format of the document, either "yaml" (the default) or
+	// "json".
+	Format string `json:"format,omitempty"`
+
+	// Document is the encoded schedule document. It replaces the whole live
+	// schedule.
+	Document string `json:"document"`
+
+	// DryRun may be set to only calculate the changes without applying
+	// them.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// ImportScheduleResponse is the response message for the ImportSchedule RPC.
+type ImportScheduleResponse struct {
+	// Diff holds the changes to the live schedule.
+	Diff repo.ScheduleDiff `json:"diff"`
+
+	// Version is the schedule version created by the import. It is unset
+	// for dry-runs.
+	Version *repo.ScheduleVersion `json:"version,omitempty"`
+}
+
+// ListDeletedOfficeHoursRequest is the request message for the
+// ListDeletedOfficeHours RPC.
+type ListDeletedOfficeHoursRequest struct {
+	// Location may be set to only return office hours of that location.
+	Location string `json:"location,omitempty"`
+}
+
+// ListDeletedOfficeHoursResponse is the response message for the
+// ListDeletedOfficeHours RPC.
+type ListDeletedOfficeHoursResponse struct {
+	// OfficeHours holds all office hours in the trash, most recently
+	// deleted ones first.
+	OfficeHours []repo.OfficeHourModel `json:"officeHours"`
+}
+
+// RestoreOfficeHourRequest is the request message for the RestoreOfficeHour
+// RPC.
+type RestoreOfficeHourRequest struct {
+	Name string `json:"name"`
+}
+
+// InvalidateHolidayCacheRequest is the request message for the
+// InvalidateHolidayCache RPC.
+type InvalidateHolidayCacheRequest struct{}
+
+// InvalidateHolidayCacheResponse is the response message for the
+// InvalidateHolidayCache RPC.
+type InvalidateHolidayCacheResponse struct{}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// validateOfficeHour validates model against others and returns a connect
+// error with code InvalidArgument if it is invalid.
+func validateOfficeHour(model *repo.OfficeHourModel, others []repo.OfficeHourModel) error {
+	if err := model.ValidateWith(others); err != nil {
+		return invalidArgument(err)
+	}
+
+	return nil
+}
+
+// invalidArgument returns a connect error with code InvalidArgument for err.
+// If err is a *repo.ValidationError, all field violations are attached to
+// the error as a google.rpc.BadRequest detail.
+func invalidArgument(err error) error {
+	return validationError(connect.CodeInvalidArgument, err)
+}
+
+// validationError returns a connect error with code for err and attaches the
+// field violations of a *repo.ValidationError.
+func validationError(code connect.Code, err error) error {
+	cerr := connect.NewError(code, err)
+
+	var verr *repo.ValidationError
+	if errors.As(err, &verr) {
+		badRequest := new(errdetails.BadRequest)
+
+		for _, v := range verr.Violations {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       v.Field,
+				Description: v.Description,
+			})
+		}
+
+		if detail, err := connect.NewErrorDetail(badRequest); err == nil {
+			cerr.AddDetail(detail)
+		}
+	}
+
+	return cerr
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+func TestValidateOfficeHourBadRequest(t *testing.T) {
+	model := &repo.OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []repo.DayTimeRange{
+			{Start: repo.DayTime{Hours: 25, Minutes: 70}, End: repo.DayTime{Hours: 12}},
+			{Start: repo.DayTime{Hours: 14}, End: repo.DayTime{Hours: 14}},
+		},
+	}
+
+	err := validateOfficeHour(model, nil)
+
+	var cerr *connect.Error
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected a connect error but got %v", err)
+	}
+
+	if cerr.Code() != connect.CodeInvalidArgument {
+		t.Errorf("expected code %s but got %s", connect.CodeInvalidArgument, cerr.Code())
+	}
+
+	if len(cerr.Details()) != 1 {
+		t.Fatalf("expected one error detail but got %d", len(cerr.Details()))
+	}
+
+	value, err := cerr.Details()[0].Value()
+	if err != nil {
+		t.Fatalf("failed to decode error detail: %s", err)
+	}
+
+	badRequest, ok := value.(*errdetails.BadRequest)
+	if !ok {
+		t.Fatalf("expected a BadRequest detail but got %T", value)
+	}
+
+	expected := []string{"timeRanges[0].start", "timeRanges[1]"}
+	if len(badRequest.FieldViolations) != len(expected) {
+		t.Fatalf("expected %d field violations but got %v", len(expected), badRequest.FieldViolations)
+	}
+
+	for idx, v := range badRequest.FieldViolations {
+		if v.Field != expected[idx] {
+			t.Errorf("expected violation %d for %q but got %q", idx, expected[idx], v.Field)
+		}
+
+		if v.Description == "" {
+			t.Errorf("expected violation %d to have a description", idx)
+		}
+	}
+}
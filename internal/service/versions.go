@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+func (svc *Service) ListScheduleVersions(ctx context.Context, req *connect.Request[ListScheduleVersionsRequest]) (*connect.Response[ListScheduleVersionsResponse], error) {
+	versions, err := svc.repo.ListVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&ListScheduleVersionsResponse{
+		Versions: versions,
+	}), nil
+}
+
+func (svc *Service) DiffScheduleVersions(ctx context.Context, req *connect.Request[DiffScheduleVersionsRequest]) (*connect.Response[repo.ScheduleDiff], error) {
+	from, err := svc.getVersion(ctx, req.Msg.From)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := svc.getVersion(ctx, req.Msg.To)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := repo.DiffSchedules(from.OfficeHours, to.OfficeHours)
+
+	return connect.NewResponse(&diff), nil
+}
+
+func (svc *Service) RollbackSchedule(ctx context.Context, req *connect.Request[RollbackScheduleRequest]) (*connect.Response[repo.ScheduleVersion], error) {
+	version, err := svc.repo.RollbackToVersion(ctx, req.Msg.Version)
+	if err != nil {
+		if errors.Is(err, repo.ErrVersionNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		}
+
+		return nil, upsertError(err)
+	}
+
+	defer svc.providers.Watcher.Trigger()
+
+	return connect.NewResponse(version), nil
+}
+
+func (svc *Service) getVersion(ctx context.Context, version int64) (*repo.ScheduleVersion, error) {
+	v, err := svc.repo.GetVersion(ctx, version)
+	if err != nil {
+		if errors.Is(err, repo.ErrVersionNotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, err)
+		}
+
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+)
+
+// WatchOpenState sends the current open state of a location and then every
+// change detected by the watcher until the client disconnects.
+func (svc *Service) WatchOpenState(ctx context.Context, req *connect.Request[WatchOpenStateRequest], stream *connect.ServerStream[WatchOpenStateResponse]) error {
+	// subscribe before resolving the current state so no change is missed
+	updates, unsubscribe := svc.providers.Watcher.Subscribe(req.Msg.Location)
+	defer unsubscribe()
+
+	state, err := svc.providers.Resolver.ResolveOpenState(ctx, time.Now().In(svc.providers.TimeZone), req.Msg.Location, 24*time.Hour)
+	if err != nil {
+		return err
+	}
+
+	last := openStateResponse(state)
+	if err := stream.Send(last); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case state := <-updates:
+			res := openStateResponse(state)
+
+			// the update might already have been sent as the initial state
+			if res.Open == last.Open && res.Mode == last.Mode && res.OfficeHour == last.OfficeHour {
+				continue
+			}
+
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+
+			last = res
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func openStateResponse(state *resolver.OpenState) *WatchOpenStateResponse {
+	res := &WatchOpenStateResponse{
+		Open: state.Open,
+		Mode: state.Mode,
+	}
+
+	if state.OfficeHour != nil {
+		res.OfficeHour = state.OfficeHour.ID.Hex()
+	}
+
+	if next := state.NextTransition; !next.IsZero() {
+		res.NextChange = &next
+	}
+
+	return res
+}
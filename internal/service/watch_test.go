@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// handlerDone closes done once a streaming handler returned.
+type handlerDone struct {
+	done chan struct{}
+}
+
+func (h handlerDone) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return next
+}
+
+func (h handlerDone) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (h handlerDone) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		defer close(h.done)
+
+		return next(ctx, conn)
+	}
+}
+
+func TestWatchOpenState(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := repo.NewMemoryStore()
+	if _, err := store.UpsertOfficeHours(ctx, &repo.OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []repo.DayTimeRange{
+			{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	svc := newTestService(store)
+
+	w := svc.providers.Watcher
+	w.Start(ctx)
+
+	done := handlerDone{done: make(chan struct{})}
+
+	_, handler := NewExtensionServiceHandler(svc, connect.WithInterceptors(done))
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	client := connect.NewClient[WatchOpenStateRequest, WatchOpenStateResponse](
+		srv.Client(),
+		srv.URL+ExtensionServiceWatchOpenStateProcedure,
+		connect.WithCodec(jsonCodec{}),
+	)
+
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+
+	stream, err := client.CallServerStream(streamCtx, connect.NewRequest(&WatchOpenStateRequest{}))
+	if err != nil {
+		t.Fatalf("failed to watch open state: %s", err)
+	}
+
+	if !stream.Receive() {
+		t.Fatalf("expected the current open state, got %v", stream.Err())
+	}
+
+	// the emergency-only mode differs from the initial state whether the
+	// test runs during the office hour or not.
+	if err := store.SetOverride(ctx, &repo.Override{
+		Open:      true,
+		Mode:      repo.ModeEmergencyOnly,
+		CreatedAt: time.Now().Add(-time.Second),
+	}); err != nil {
+		t.Fatalf("failed to set override: %s", err)
+	}
+
+	w.Trigger()
+
+	if !stream.Receive() {
+		t.Fatalf("expected the changed open state, got %v", stream.Err())
+	}
+
+	if msg := stream.Msg(); !msg.Open || msg.Mode != repo.ModeEmergencyOnly {
+		t.Errorf("expected the override to be sent, got %+v", msg)
+	}
+
+	// disconnecting the client must end the handler so it unsubscribes from
+	// the watcher.
+	cancelStream()
+	stream.Close()
+
+	select {
+	case <-done.done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected WatchOpenState to return after the client disconnected")
+	}
+}
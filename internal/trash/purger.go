@@ -0,0 +1,57 @@
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+// Purger permanently deletes office hours that have been in the trash for
+// longer than the retention period.
+type Purger struct {
+	repo      repo.OfficeHourStore
+	retention time.Duration
+}
+
+// NewPurger returns a new purger. A retention of zero keeps deleted office
+// hours forever.
+func NewPurger(repo repo.OfficeHourStore, retention time.Duration) *Purger {
+	return &Purger{
+		repo:      repo,
+		retention: retention,
+	}
+}
+
+// Start purges the trash once an hour until ctx is cancelled. It should only
+// be called on the elected leader.
+func (p *Purger) Start(ctx context.Context) {
+	if p.retention <= 0 {
+		return
+	}
+
+	go func() {
+		for {
+			p.purge(ctx)
+
+			select {
+			case <-time.After(time.Hour):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	count, err := p.repo.PurgeDeletedOfficeHours(ctx, time.Now().Add(-p.retention))
+	if err != nil {
+		slog.Error("failed to purge deleted office hours", "error", err)
+		return
+	}
+
+	if count > 0 {
+		slog.Info("purged deleted office hours", "count", count)
+	}
+}
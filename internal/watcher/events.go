@@ -0,0 +1,48 @@
+package watcher
+
+import (
+	"time"
+
+	extv1 "github.com/tierklinik-dobersberg/office-hours-service/gen/go/tkd/office_hours/v1"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newLocationOpenChangeEvent returns a LocationOpenChangeEvent for state of
+// location that began at since.
+func newLocationOpenChangeEvent(location string, state *resolver.OpenState, since time.Time) *extv1.LocationOpenChangeEvent {
+	event := &extv1.LocationOpenChangeEvent{
+		Location: location,
+		IsOpen:   state.Open,
+		Mode:     string(state.Mode),
+		Since:    timestamppb.New(since),
+		Override: state.Override != nil,
+	}
+
+	if state.OfficeHour != nil {
+		event.OfficeHour = state.OfficeHour.ID.Hex()
+	}
+
+	return event
+}
+
+// newWarningEvent returns a ClosingWarningEvent or OpeningWarningEvent,
+// depending on kind, for location that changes its open state at changeAt,
+// lead before the change.
+func newWarningEvent(kind string, location string, changeAt time.Time, lead time.Duration) proto.Message {
+	if kind == ClosingWarning {
+		return &extv1.ClosingWarningEvent{
+			Location: location,
+			ClosesAt: timestamppb.New(changeAt),
+			LeadTime: durationpb.New(lead),
+		}
+	}
+
+	return &extv1.OpeningWarningEvent{
+		Location: location,
+		OpensAt:  timestamppb.New(changeAt),
+		LeadTime: durationpb.New(lead),
+	}
+}
@@ -0,0 +1,137 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	eventsv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/events/v1"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+)
+
+const (
+	// minRetryBackoff is the delay after the first failed delivery attempt.
+	minRetryBackoff = time.Second
+
+	// maxRetryBackoff is the maximum delay between two delivery attempts.
+	maxRetryBackoff = 5 * time.Minute
+
+	// defaultPollInterval is the default interval at which the outbox is
+	// checked for events enqueued by other replicas.
+	defaultPollInterval = time.Minute
+)
+
+// SetMaxDeliveryAttempts configures the number of delivery attempts after
+// which an event is given up. Dead events are kept in the outbox, together
+// with the last delivery error, for 30 days. Zero, the default, retries
+// events forever.
+func (w *Watcher) SetMaxDeliveryAttempts(n int) {
+	w.maxDeliveryAttempts = n
+}
+
+// SetPollInterval configures how often the leader checks the outbox for
+// events enqueued by other replicas. It defaults to a minute and only
+// matters if the store does not notify the leader using Dispatch.
+func (w *Watcher) SetPollInterval(d time.Duration) {
+	w.pollInterval = d
+}
+
+// dispatchLoop delivers all events from the outbox to the event service.
+// Events are delivered in the order they have been enqueued and failed
+// deliveries are retried with an exponential backoff of at most
+// maxRetryBackoff. If a maximum number of delivery attempts is configured,
+// events that cannot be delivered are marked as dead and skipped so they do
+// not block the following events forever.
+//
+// Events enqueued by this replica, or by another replica if the store
+// notifies the leader using Dispatch, are delivered immediately. Otherwise
+// they are picked up at most one poll interval after they have been
+// enqueued. While the event service is unavailable, an event is delivered
+// at most maxRetryBackoff after the service recovered. Since events are
+// delivered in order, an event that cannot be delivered delays all
+// following events until it is delivered or, if configured, given up.
+func (w *Watcher) dispatchLoop(ctx context.Context) {
+	for {
+		// poll regularly so events enqueued by other processes are
+		// delivered as well.
+		interval := w.pollInterval
+
+		if next := w.dispatchPending(ctx); !next.IsZero() {
+			interval = time.Until(next)
+		}
+
+		select {
+		case <-time.After(interval):
+		case <-w.dispatch:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatchPending tries to deliver all pending events and returns the time
+// at which the next delivery attempt is due.
+func (w *Watcher) dispatchPending(ctx context.Context) time.Time {
+	entries, err := w.repo.PendingEvents(ctx)
+	if err != nil {
+		slog.Error("failed to load pending events from outbox", "error", err)
+
+		return time.Time{}
+	}
+
+	for _, entry := range entries {
+		// stop at the first event that is not yet due for a retry so events
+		// are not delivered out of order.
+		if entry.NextAttempt.After(time.Now()) {
+			return entry.NextAttempt
+		}
+
+		pb, err := entry.Any()
+		if err != nil {
+			// the event can never be delivered
+			w.giveUp(ctx, entry, err)
+			continue
+		}
+
+		_, err = w.eventClient.Publish(ctx, connect.NewRequest(&eventsv1.Event{
+			Event: pb,
+		}))
+
+		if err != nil && w.maxDeliveryAttempts > 0 && entry.Attempts+1 >= w.maxDeliveryAttempts {
+			w.giveUp(ctx, entry, err)
+			continue
+		}
+
+		if err != nil {
+			// events may be retried forever so limit the shift to prevent
+			// the backoff from overflowing.
+			backoff := min(minRetryBackoff<<min(entry.Attempts, 32), maxRetryBackoff)
+
+			next := time.Now().Add(backoff)
+
+			slog.Error("failed to deliver event", "key", entry.Key, "attempts", entry.Attempts+1, "nextAttempt", next.Format(time.RFC3339), "error", err)
+
+			if err := w.repo.MarkEventFailed(ctx, entry.Key, next, err); err != nil {
+				slog.Error("failed to update outbox entry", "key", entry.Key, "error", err)
+			}
+
+			return next
+		}
+
+		if err := w.repo.MarkEventDelivered(ctx, entry.Key); err != nil {
+			slog.Error("failed to mark event as delivered", "key", entry.Key, "error", err)
+		}
+	}
+
+	return time.Time{}
+}
+
+// giveUp marks entry as dead after its final failed delivery attempt.
+func (w *Watcher) giveUp(ctx context.Context, entry repo.OutboxEntry, deliveryErr error) {
+	slog.Error("giving up delivering event", "key", entry.Key, "attempts", entry.Attempts+1, "error", deliveryErr)
+
+	if err := w.repo.MarkEventDead(ctx, entry.Key, deliveryErr); err != nil {
+		slog.Error("failed to update outbox entry", "key", entry.Key, "error", err)
+	}
+}
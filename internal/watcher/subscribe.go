@@ -0,0 +1,57 @@
+package watcher
+
+import (
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+)
+
+// subscriber receives open state changes of a single location.
+type subscriber struct {
+	location string
+
+	// ch only ever holds the most recent state so a slow subscriber
+	// cannot block the watcher.
+	ch chan *resolver.OpenState
+}
+
+// Subscribe returns a channel that receives the open state of location
+// whenever it changes. Subscribers that do not keep up only receive the most
+// recent state. The returned function must be called to unsubscribe.
+func (w *Watcher) Subscribe(location string) (<-chan *resolver.OpenState, func()) {
+	sub := &subscriber{
+		location: location,
+		ch:       make(chan *resolver.OpenState, 1),
+	}
+
+	w.subscriberLock.Lock()
+	defer w.subscriberLock.Unlock()
+
+	w.subscribers[sub] = struct{}{}
+
+	return sub.ch, func() {
+		w.subscriberLock.Lock()
+		defer w.subscriberLock.Unlock()
+
+		delete(w.subscribers, sub)
+	}
+}
+
+// broadcast sends state to all subscribers of location.
+func (w *Watcher) broadcast(location string, state *resolver.OpenState) {
+	w.subscriberLock.Lock()
+	defer w.subscriberLock.Unlock()
+
+	for sub := range w.subscribers {
+		if sub.location != location {
+			continue
+		}
+
+		// Replace a state that has not been consumed yet. broadcast is only
+		// called from the watch loop so there is no other sender.
+		select {
+		case <-sub.ch:
+		default:
+		}
+
+		sub.ch <- state
+	}
+}
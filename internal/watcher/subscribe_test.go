@@ -0,0 +1,65 @@
+package watcher
+
+import (
+	"testing"
+
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+)
+
+func TestSubscribeLatestWins(t *testing.T) {
+	w, _, _ := newTestWatcher(t, LeadTimes{})
+
+	updates, unsubscribe := w.Subscribe("")
+	defer unsubscribe()
+
+	other, unsubscribeOther := w.Subscribe("surgery")
+	defer unsubscribeOther()
+
+	// the subscriber does not consume the first states so broadcast must
+	// not block and only the most recent state is kept.
+	w.broadcast("", &resolver.OpenState{Open: true})
+	w.broadcast("", &resolver.OpenState{Open: false})
+	w.broadcast("", &resolver.OpenState{Open: true, Mode: repo.ModePhoneOnly})
+
+	select {
+	case state := <-updates:
+		if !state.Open || state.Mode != repo.ModePhoneOnly {
+			t.Errorf("expected the most recent state, got %+v", state)
+		}
+	default:
+		t.Fatalf("expected a state to be sent")
+	}
+
+	select {
+	case state := <-updates:
+		t.Errorf("expected only the most recent state, got %+v", state)
+	default:
+	}
+
+	// subscribers of other locations are not notified
+	select {
+	case state := <-other:
+		t.Errorf("unexpected state for another location: %+v", state)
+	default:
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	w, _, _ := newTestWatcher(t, LeadTimes{})
+
+	updates, unsubscribe := w.Subscribe("")
+	unsubscribe()
+
+	if len(w.subscribers) != 0 {
+		t.Fatalf("expected the subscriber to be removed, got %d subscribers", len(w.subscribers))
+	}
+
+	w.broadcast("", &resolver.OpenState{Open: true})
+
+	select {
+	case state := <-updates:
+		t.Errorf("unexpected state after unsubscribing: %+v", state)
+	default:
+	}
+}
@@ -2,99 +2,200 @@ package watcher
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/bufbuild/connect-go"
-	eventsv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/events/v1"
 	"github.com/tierklinik-dobersberg/apis/gen/go/tkd/events/v1/eventsv1connect"
 	office_hoursv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/office_hours/v1"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
 	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
-	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/proto"
 )
 
+// LeadTimes configures how long before a location closes or opens the
+// watcher publishes a warning event.
+type LeadTimes struct {
+	Closing []time.Duration
+	Opening []time.Duration
+}
+
 type Watcher struct {
+	repo        repo.OfficeHourStore
 	resolver    *resolver.Resolver
 	eventClient eventsv1connect.EventServiceClient
+	tz          *time.Location
+	leadTimes   LeadTimes
+
+	trigger  chan struct{}
+	dispatch chan struct{}
+
+	// maxDeliveryAttempts is the number of delivery attempts after which an
+	// event is marked as dead. Zero retries events forever.
+	maxDeliveryAttempts int
 
-	trigger chan struct{}
+	// pollInterval is the interval at which the leader looks for events
+	// enqueued by other replicas without being notified.
+	pollInterval time.Duration
+
+	// leading holds the current leadership term while this replica is the
+	// elected leader and zero otherwise. Only the leader publishes events.
+	// terms counts the leadership terms so a term that ended does not clear
+	// the state of a later one.
+	leading atomic.Int64
+	terms   atomic.Int64
+
+	subscriberLock sync.Mutex
+	subscribers    map[*subscriber]struct{}
 }
 
-func New(r *resolver.Resolver, eventClient eventsv1connect.EventServiceClient) *Watcher {
+func New(repo repo.OfficeHourStore, r *resolver.Resolver, eventClient eventsv1connect.EventServiceClient, tz *time.Location, leadTimes LeadTimes) *Watcher {
 	w := &Watcher{
+		repo:        repo,
 		resolver:    r,
 		eventClient: eventClient,
-		trigger:     make(chan struct{}),
+		tz:          tz,
+		leadTimes:   leadTimes,
+		trigger:     make(chan struct{}, 1),
+		dispatch:    make(chan struct{}, 1),
+		subscribers: make(map[*subscriber]struct{}),
+
+		pollInterval: defaultPollInterval,
 	}
 
 	return w
 }
 
+// Kinds of warning events published by the watcher.
+const (
+	ClosingWarning = "ClosingWarning"
+	OpeningWarning = "OpeningWarning"
+)
+
+// warningKey identifies a published warning event.
+type warningKey struct {
+	location string
+	kind     string
+	changeAt time.Time
+	leadTime time.Duration
+}
+
+// publishedState is the last open state published for a location.
+type publishedState struct {
+	open bool
+	mode repo.OpeningMode
+}
+
+// Start starts watching the open state of all locations. Every replica
+// watches the open state so subscribers can be notified about state changes
+// but events are only published while the replica is leading (see Lead).
 func (w *Watcher) Start(ctx context.Context) {
-	var wasOpen *bool
 	go func() {
+		// published holds the last published open state for each location.
+		published := make(map[string]publishedState)
+
+		// broadcasted holds the last open state sent to subscribers for each
+		// location.
+		broadcasted := make(map[string]publishedState)
+
+		// warned holds all warnings that have already been published.
+		warned := make(map[warningKey]struct{})
+
+		// lastTerm is the leadership term seen in the previous iteration.
+		var lastTerm int64
+
 		for {
 			interval := time.Minute
 
-			now := time.Now()
-
-			// fetch all office hours for today
-			hours, err := w.resolver.ResolveOfficeHours(ctx, now)
-			if err == nil {
-				var (
-					min         time.Time
-					isOpen      bool
-					appliedHour *office_hoursv1.OfficeHour
-				)
-
-				for _, h := range hours {
-					for _, tr := range h.TimeRanges {
-						start := tr.Start.At(now)
-						end := tr.End.At(now)
-
-						if start.After(now) && (start.Before(min) || min.IsZero()) {
-							min = start
-						}
-
-						if end.After(now) && (end.Before(min) || min.IsZero()) {
-							min = end
-						}
-
-						// check if the office hour currntly applies
-						if tr.At(now).Includes(now) {
-							isOpen = true
-							appliedHour = h
-						}
-					}
+			now := time.Now().In(w.tz)
+
+			// Forget about everything published before if we just became the
+			// leader since another replica might have published events in
+			// the meantime. Duplicates are detected by the outbox.
+			term := w.leading.Load()
+			if term != 0 && term != lastTerm {
+				clear(published)
+				clear(warned)
+			}
+			lastTerm = term
+
+			leading := term != 0
+
+			locations, err := w.repo.ListLocations(ctx)
+			if err != nil {
+				slog.Error("failed to list office-hour locations", "error", err)
+
+				// still check the default location
+				locations = []string{""}
+			}
+
+			var min time.Time
+
+			seen := make(map[string]struct{}, len(locations))
+			for _, location := range locations {
+				seen[location] = struct{}{}
+
+				state, err := w.check(ctx, now, location)
+				if err != nil {
+					slog.Error("failed to resolve office hours", "location", location, "error", err)
+					continue
+				}
+
+				if next := state.NextTransition; !next.IsZero() && (next.Before(min) || min.IsZero()) {
+					min = next
+				}
+
+				// Notify subscribers if either the open state or the opening
+				// mode changed.
+				current := publishedState{
+					open: state.Open,
+					mode: state.Mode,
+				}
+
+				if last, ok := broadcasted[location]; !ok || last != current {
+					w.broadcast(location, state)
+					broadcasted[location] = current
 				}
 
-				if !min.IsZero() {
-					// get the expect interval at which the office-hour state will change
-					interval = time.Until(min)
-					slog.Info("waiting for office-hour change", "expectedChange", min.Format(time.RFC3339))
+				if !leading {
+					continue
 				}
 
-				// Publish an OpenChangeEvent
-				if wasOpen == nil || *wasOpen != isOpen {
-					wasOpen = &isOpen
-
-					pb, err := anypb.New(&office_hoursv1.OpenChangeEvent{
-						IsOpen:     isOpen,
-						OfficeHour: appliedHour,
-					})
-					if err == nil {
-						_, err = w.eventClient.Publish(ctx, connect.NewRequest(&eventsv1.Event{
-							Event: pb,
-						}))
-					}
-
-					if err != nil {
-						slog.Error("failed to publish OpenChangeEvent", "error", err)
-					}
+				if err := w.publishChange(ctx, now, location, state, published); err != nil {
+					slog.Error("failed to publish open state events", "location", location, "error", err)
 				}
 
-			} else {
-				slog.Error("failed to resolve office hours", "error", err)
+				if next := w.warn(ctx, now, location, state, warned); !next.IsZero() && (next.Before(min) || min.IsZero()) {
+					min = next
+				}
+			}
+
+			// forget about warnings for changes that already happened
+			for key := range warned {
+				if !key.changeAt.After(now) {
+					delete(warned, key)
+				}
+			}
+
+			// forget about locations that do not exist anymore
+			for location := range published {
+				if _, ok := seen[location]; !ok {
+					delete(published, location)
+				}
+			}
+
+			for location := range broadcasted {
+				if _, ok := seen[location]; !ok {
+					delete(broadcasted, location)
+				}
+			}
+
+			if !min.IsZero() {
+				// get the expect interval at which the office-hour state will change
+				interval = time.Until(min)
+				slog.Info("waiting for office-hour change", "expectedChange", min.Format(time.RFC3339))
 			}
 
 			select {
@@ -112,6 +213,208 @@ func (w *Watcher) Start(ctx context.Context) {
 	}()
 }
 
+// Lead publishes events and delivers them to the event service until ctx is
+// cancelled. It is called whenever this replica is elected as the leader.
+func (w *Watcher) Lead(ctx context.Context) {
+	if w.eventClient == nil {
+		return
+	}
+
+	term := w.terms.Add(1)
+	w.leading.Store(term)
+
+	go func() {
+		<-ctx.Done()
+
+		// we might already lead again in a later term
+		w.leading.CompareAndSwap(term, 0)
+	}()
+
+	go w.dispatchLoop(ctx)
+
+	// immediately publish the current state
+	w.Trigger()
+}
+
+// PublishState immediately publishes the current open state of location,
+// even if this replica is not the leader. The leader detects that the event
+// has already been enqueued using its idempotency key. Events enqueued on
+// another replica are delivered by the leader once it is notified using
+// Dispatch.
+func (w *Watcher) PublishState(ctx context.Context, location string) error {
+	if w.eventClient == nil {
+		return nil
+	}
+
+	now := time.Now().In(w.tz)
+
+	state, err := w.check(ctx, now, location)
+	if err != nil {
+		return err
+	}
+
+	since := transitionTime(now, state)
+	key := transitionKey(location, state, since)
+
+	// The OpenChangeEvent is left to the leader which knows whether the
+	// open state actually changed.
+	return w.publishWithKey(ctx, key, location, state, since, false)
+}
+
+// check resolves the open state of location at now.
+func (w *Watcher) check(ctx context.Context, now time.Time, location string) (*resolver.OpenState, error) {
+	// look one day ahead so changes after midnight are detected as well
+	return w.resolver.ResolveOpenState(ctx, now, location, 24*time.Hour)
+}
+
+// warn publishes all due warning events for the next state change of location
+// and returns the time at which the next warning is due.
+func (w *Watcher) warn(ctx context.Context, now time.Time, location string, state *resolver.OpenState, warned map[warningKey]struct{}) time.Time {
+	kind, changeAt, leadTimes := OpeningWarning, state.NextOpen, w.leadTimes.Opening
+	if state.Open {
+		kind, changeAt, leadTimes = ClosingWarning, state.NextClose, w.leadTimes.Closing
+	}
+
+	if changeAt.IsZero() || !changeAt.After(now) {
+		return time.Time{}
+	}
+
+	var next time.Time
+	for _, lead := range leadTimes {
+		warnAt := changeAt.Add(-lead)
+
+		if warnAt.After(now) {
+			if warnAt.Before(next) || next.IsZero() {
+				next = warnAt
+			}
+
+			continue
+		}
+
+		key := warningKey{
+			location: location,
+			kind:     kind,
+			changeAt: changeAt,
+			leadTime: lead,
+		}
+
+		if _, ok := warned[key]; ok {
+			continue
+		}
+
+		if err := w.enqueue(ctx, fmt.Sprintf("%s/%s/%s/%s", kind, location, changeAt.Format(time.RFC3339), lead), newWarningEvent(kind, location, changeAt, lead)); err != nil {
+			slog.Error("failed to publish warning event", "kind", kind, "location", location, "error", err)
+			continue
+		}
+
+		warned[key] = struct{}{}
+	}
+
+	return next
+}
+
+// publishChange publishes the state of location if either the open state or
+// the opening mode changed since the last state recorded in published. The
+// state is only recorded once it has been published successfully.
+func (w *Watcher) publishChange(ctx context.Context, now time.Time, location string, state *resolver.OpenState, published map[string]publishedState) error {
+	current := publishedState{
+		open: state.Open,
+		mode: state.Mode,
+	}
+
+	last, ok := published[location]
+	if ok && last == current {
+		return nil
+	}
+
+	if err := w.publish(ctx, now, location, state, !ok || last.open != current.open); err != nil {
+		return err
+	}
+
+	published[location] = current
+
+	return nil
+}
+
+// publish enqueues a LocationOpenChangeEvent for location. If openChanged is
+// set and location is the default location an OpenChangeEvent is enqueued as
+// well.
+//
+// The OpenChangeEvent carries neither the location nor the opening mode so it
+// is only published for the default location and only if the open state
+// changed. This keeps its contract for existing consumers. Consumers that
+// need the transitions of every location or changes of the opening mode
+// subscribe to the LocationOpenChangeEvent.
+func (w *Watcher) publish(ctx context.Context, now time.Time, location string, state *resolver.OpenState, openChanged bool) error {
+	since := transitionTime(now, state)
+
+	return w.publishWithKey(ctx, transitionKey(location, state, since), location, state, since, openChanged)
+}
+
+func (w *Watcher) publishWithKey(ctx context.Context, key string, location string, state *resolver.OpenState, since time.Time, openChanged bool) error {
+	if err := w.enqueue(ctx, "LocationOpenChangeEvent/"+key, newLocationOpenChangeEvent(location, state, since)); err != nil {
+		return err
+	}
+
+	if location != "" || !openChanged {
+		return nil
+	}
+
+	var appliedHour *office_hoursv1.OfficeHour
+	if state.OfficeHour != nil {
+		appliedHour = state.OfficeHour.ToProto()
+	}
+
+	return w.enqueue(ctx, "OpenChangeEvent/"+key, &office_hoursv1.OpenChangeEvent{
+		IsOpen:     state.Open,
+		OfficeHour: appliedHour,
+	})
+}
+
+// transitionTime returns the time at which state began. If it is unknown,
+// the start of the day of now is used.
+func transitionTime(now time.Time, state *resolver.OpenState) time.Time {
+	if !state.Since.IsZero() {
+		return state.Since
+	}
+
+	year, month, day := now.Date()
+
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+}
+
+// transitionKey returns the idempotency key for the events published for a
+// state transition. The key identifies the transition so re-evaluating the
+// same state (for example after a restart) does not publish it twice.
+func transitionKey(location string, state *resolver.OpenState, since time.Time) string {
+	return fmt.Sprintf("%s/%t/%s/%s", location, state.Open, state.Mode, since.Format(time.RFC3339))
+}
+
+// enqueue stores msg in the outbox and notifies the dispatcher.
+func (w *Watcher) enqueue(ctx context.Context, key string, msg proto.Message) error {
+	if err := w.repo.EnqueueEvent(ctx, key, msg); err != nil {
+		return err
+	}
+
+	w.Dispatch()
+
+	return nil
+}
+
+// Dispatch causes the outbox to be delivered immediately if this replica is
+// the leader. It is called for events enqueued by other replicas, for
+// example by PublishState, if the store supports change notifications.
+func (w *Watcher) Dispatch() {
+	if w == nil {
+		return
+	}
+
+	select {
+	case w.dispatch <- struct{}{}:
+	default:
+	}
+}
+
 func (w *Watcher) Trigger() {
 	if w == nil {
 		return
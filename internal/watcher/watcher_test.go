@@ -0,0 +1,566 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	eventsv1 "github.com/tierklinik-dobersberg/apis/gen/go/tkd/events/v1"
+	"github.com/tierklinik-dobersberg/apis/gen/go/tkd/events/v1/eventsv1connect"
+	extv1 "github.com/tierklinik-dobersberg/office-hours-service/gen/go/tkd/office_hours/v1"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/repo"
+	"github.com/tierklinik-dobersberg/office-hours-service/internal/resolver"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type noHolidays struct{}
+
+func (noHolidays) PublicHolidays(ctx context.Context, year int, month time.Month) ([]string, error) {
+	return nil, nil
+}
+
+// fakeEventClient records all published events. It fails while err is set
+// and always rejects events of kind reject (see eventKind).
+type fakeEventClient struct {
+	eventsv1connect.EventServiceClient
+
+	err       error
+	reject    string
+	published []*eventsv1.Event
+}
+
+func (c *fakeEventClient) Publish(ctx context.Context, req *connect.Request[eventsv1.Event]) (*connect.Response[emptypb.Empty], error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	if eventKind(req.Msg.Event) == c.reject {
+		return nil, errors.New("rejected")
+	}
+
+	c.published = append(c.published, req.Msg)
+
+	return connect.NewResponse(new(emptypb.Empty)), nil
+}
+
+// eventKind decodes event and returns the name of its message. It returns an
+// empty string if the event cannot be decoded.
+func eventKind(event *anypb.Any) string {
+	msg, err := event.UnmarshalNew()
+	if err != nil {
+		return ""
+	}
+
+	return string(proto.MessageName(msg))
+}
+
+// locationOpenChangeEvent is the kind of LocationOpenChangeEvent events as
+// returned by eventKind.
+var locationOpenChangeEvent = string(proto.MessageName(new(extv1.LocationOpenChangeEvent)))
+
+func newTestWatcher(t *testing.T, leadTimes LeadTimes) (*Watcher, *repo.MemoryStore, *fakeEventClient) {
+	t.Helper()
+
+	store := repo.NewMemoryStore()
+
+	if _, err := store.UpsertOfficeHours(context.Background(), &repo.OfficeHourModel{
+		DayOfWeek: time.Monday,
+		TimeRanges: []repo.DayTimeRange{
+			{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 12}},
+		},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	client := new(fakeEventClient)
+	r := resolver.NewResolver(store, noHolidays{}, 0, time.UTC)
+
+	return New(store, r, client, time.UTC, leadTimes), store, client
+}
+
+func TestWarn(t *testing.T) {
+	ctx := context.Background()
+
+	w, store, _ := newTestWatcher(t, LeadTimes{
+		Closing: []time.Duration{30 * time.Minute, 10 * time.Minute},
+		Opening: []time.Duration{15 * time.Minute},
+	})
+
+	cases := []struct {
+		name     string
+		now      time.Time
+		keys     []string
+		kind     string
+		nextWarn time.Time
+	}{
+		{
+			name:     "closing warning due",
+			now:      time.Date(2024, time.June, 3, 11, 40, 0, 0, time.UTC),
+			keys:     []string{"ClosingWarning//2024-06-03T12:00:00Z/30m0s"},
+			kind:     "tkd.office_hours.v1.ClosingWarningEvent",
+			nextWarn: time.Date(2024, time.June, 3, 11, 50, 0, 0, time.UTC),
+		},
+		{
+			name: "opening warning due",
+			now:  time.Date(2024, time.June, 3, 7, 50, 0, 0, time.UTC),
+			keys: []string{"OpeningWarning//2024-06-03T08:00:00Z/15m0s"},
+			kind: "tkd.office_hours.v1.OpeningWarningEvent",
+		},
+		{
+			name:     "no warning due yet",
+			now:      time.Date(2024, time.June, 3, 7, 0, 0, 0, time.UTC),
+			nextWarn: time.Date(2024, time.June, 3, 7, 45, 0, 0, time.UTC),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			state, err := w.check(ctx, c.now, "")
+			if err != nil {
+				t.Fatalf("failed to resolve open state: %s", err)
+			}
+
+			warned := make(map[warningKey]struct{})
+
+			if next := w.warn(ctx, c.now, "", state, warned); !next.Equal(c.nextWarn) {
+				t.Errorf("expected next warning at %s but got %s", c.nextWarn, next)
+			}
+
+			// warnings are only published once
+			w.warn(ctx, c.now, "", state, warned)
+
+			entries, err := store.PendingEvents(ctx)
+			if err != nil {
+				t.Fatalf("failed to load pending events: %s", err)
+			}
+
+			if len(entries) != len(c.keys) {
+				t.Fatalf("expected %d events but got %d", len(c.keys), len(entries))
+			}
+
+			for idx, entry := range entries {
+				if entry.Key != c.keys[idx] {
+					t.Errorf("expected event key %q but got %q", c.keys[idx], entry.Key)
+				}
+
+				pb, err := entry.Any()
+				if err != nil {
+					t.Fatalf("failed to decode event: %s", err)
+				}
+
+				if kind := eventKind(pb); kind != c.kind {
+					t.Errorf("expected event kind %q but got %q", c.kind, kind)
+				}
+
+				if err := store.MarkEventDelivered(ctx, entry.Key); err != nil {
+					t.Fatalf("failed to mark event as delivered: %s", err)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchPending(t *testing.T) {
+	ctx := context.Background()
+
+	w, store, client := newTestWatcher(t, LeadTimes{})
+
+	now := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+	state, err := w.check(ctx, now, "")
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if err := w.publish(ctx, now, "", state, true); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+
+	// a failed delivery is retried later and blocks all following events
+	client.err = errors.New("unavailable")
+
+	before := time.Now()
+	if next := w.dispatchPending(ctx); !next.After(before) {
+		t.Fatalf("expected a retry to be scheduled, got %s", next)
+	}
+
+	entries, _ := store.PendingEvents(ctx)
+	if len(entries) != 2 || entries[0].Attempts != 1 || entries[1].Attempts != 0 {
+		t.Fatalf("expected the first of two events to be attempted once, got %+v", entries)
+	}
+
+	// nothing is delivered before the retry is due
+	client.err = nil
+
+	w.dispatchPending(ctx)
+
+	if len(client.published) != 0 {
+		t.Fatalf("expected no events to be delivered before the retry is due")
+	}
+
+	// make the retry due
+	if err := store.MarkEventFailed(ctx, entries[0].Key, time.Now().Add(-time.Second), errors.New("unavailable")); err != nil {
+		t.Fatalf("failed to update outbox entry: %s", err)
+	}
+
+	if next := w.dispatchPending(ctx); !next.IsZero() {
+		t.Errorf("expected no further delivery attempts, got %s", next)
+	}
+
+	if entries, _ := store.PendingEvents(ctx); len(entries) != 0 {
+		t.Errorf("expected all events to be delivered, got %d pending", len(entries))
+	}
+
+	expected := []string{
+		locationOpenChangeEvent,
+		"tkd.office_hours.v1.OpenChangeEvent",
+	}
+
+	if len(client.published) != len(expected) {
+		t.Fatalf("expected %d events to be delivered but got %d", len(expected), len(client.published))
+	}
+
+	for idx, event := range client.published {
+		if kind := eventKind(event.Event); kind != expected[idx] {
+			t.Errorf("expected event %d to be of kind %q but got %q", idx, expected[idx], kind)
+		}
+	}
+}
+
+func TestPublishLocation(t *testing.T) {
+	ctx := context.Background()
+
+	w, store, _ := newTestWatcher(t, LeadTimes{})
+
+	now := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+
+	for _, location := range []string{"", "branch"} {
+		state, err := w.check(ctx, now, location)
+		if err != nil {
+			t.Fatalf("failed to resolve open state: %s", err)
+		}
+
+		if err := w.publish(ctx, now, location, state, true); err != nil {
+			t.Fatalf("failed to publish: %s", err)
+		}
+	}
+
+	entries, err := store.PendingEvents(ctx)
+	if err != nil {
+		t.Fatalf("failed to load pending events: %s", err)
+	}
+
+	// the OpenChangeEvent is only published for the default location
+	expected := []struct {
+		kind     string
+		location string
+		open     bool
+	}{
+		{locationOpenChangeEvent, "", true},
+		{"tkd.office_hours.v1.OpenChangeEvent", "", true},
+		{locationOpenChangeEvent, "branch", false},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d events but got %d", len(expected), len(entries))
+	}
+
+	for idx, entry := range entries {
+		pb, err := entry.Any()
+		if err != nil {
+			t.Fatalf("failed to decode event: %s", err)
+		}
+
+		if kind := eventKind(pb); kind != expected[idx].kind {
+			t.Fatalf("expected event %d to be of kind %q but got %q", idx, expected[idx].kind, kind)
+		}
+
+		if expected[idx].kind != locationOpenChangeEvent {
+			continue
+		}
+
+		event := new(extv1.LocationOpenChangeEvent)
+		if err := pb.UnmarshalTo(event); err != nil {
+			t.Fatalf("failed to decode event: %s", err)
+		}
+
+		if event.Location != expected[idx].location {
+			t.Errorf("expected event %d to be for location %q but got %q", idx, expected[idx].location, event.Location)
+		}
+
+		if event.IsOpen != expected[idx].open {
+			t.Errorf("expected event %d to have open=%t", idx, expected[idx].open)
+		}
+
+		if expected[idx].open && event.Mode != string(repo.ModeConsultation) {
+			t.Errorf("expected event %d to carry the opening mode, got %q", idx, event.Mode)
+		}
+	}
+}
+
+func TestDispatchPendingRetriesForever(t *testing.T) {
+	ctx := context.Background()
+
+	w, store, client := newTestWatcher(t, LeadTimes{})
+
+	now := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+	state, err := w.check(ctx, now, "")
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if err := w.publish(ctx, now, "", state, true); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+
+	client.reject = locationOpenChangeEvent
+
+	entries, _ := store.PendingEvents(ctx)
+
+	for attempt := 1; attempt < 100; attempt++ {
+		if err := store.MarkEventFailed(ctx, entries[0].Key, time.Now().Add(-time.Second), errors.New("rejected")); err != nil {
+			t.Fatalf("failed to update outbox entry: %s", err)
+		}
+	}
+
+	next := w.dispatchPending(ctx)
+
+	entries, _ = store.PendingEvents(ctx)
+	if len(entries) != 2 || entries[0].Attempts != 100 {
+		t.Fatalf("expected the rejected event to stay pending, got %d pending events", len(entries))
+	}
+
+	// the backoff is capped instead of overflowing
+	if wait := time.Until(next); wait <= 0 || wait > maxRetryBackoff {
+		t.Errorf("expected the next attempt within %s, got %s", maxRetryBackoff, wait)
+	}
+
+	if len(client.published) != 0 {
+		t.Errorf("expected following events to wait for the rejected one, got %d events", len(client.published))
+	}
+}
+
+func TestDispatchPendingGivesUp(t *testing.T) {
+	ctx := context.Background()
+
+	w, store, client := newTestWatcher(t, LeadTimes{})
+	w.SetMaxDeliveryAttempts(20)
+
+	now := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.UTC)
+	state, err := w.check(ctx, now, "")
+	if err != nil {
+		t.Fatalf("failed to resolve open state: %s", err)
+	}
+
+	if err := w.publish(ctx, now, "", state, true); err != nil {
+		t.Fatalf("failed to publish: %s", err)
+	}
+
+	client.reject = locationOpenChangeEvent
+
+	entries, _ := store.PendingEvents(ctx)
+
+	// the rejected event is retried until the last attempt
+	for attempt := 1; attempt < 20; attempt++ {
+		if err := store.MarkEventFailed(ctx, entries[0].Key, time.Now().Add(-time.Second), errors.New("rejected")); err != nil {
+			t.Fatalf("failed to update outbox entry: %s", err)
+		}
+	}
+
+	w.dispatchPending(ctx)
+
+	if entries, _ := store.PendingEvents(ctx); len(entries) != 0 {
+		t.Fatalf("expected the rejected event to be given up, got %d pending events", len(entries))
+	}
+
+	// following events are not blocked by the dead one
+	if len(client.published) != 1 || eventKind(client.published[0].Event) == locationOpenChangeEvent {
+		t.Errorf("expected the following event to be delivered, got %d events", len(client.published))
+	}
+}
+
+func TestLeadTerms(t *testing.T) {
+	w, _, _ := newTestWatcher(t, LeadTimes{})
+
+	first, cancelFirst := context.WithCancel(context.Background())
+	w.Lead(first)
+
+	// lose and immediately regain the leadership
+	cancelFirst()
+
+	second, cancelSecond := context.WithCancel(context.Background())
+	defer cancelSecond()
+
+	w.Lead(second)
+
+	// give the first term time to clean up
+	time.Sleep(10 * time.Millisecond)
+
+	if w.leading.Load() != 2 {
+		t.Fatalf("expected to lead in the second term, got term %d", w.leading.Load())
+	}
+
+	cancelSecond()
+
+	for deadline := time.Now().Add(time.Second); w.leading.Load() != 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected to step down after the second term ended")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatchEventsOfOtherReplicas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leader, store, _ := newTestWatcher(t, LeadTimes{})
+
+	// a second replica sharing the same store
+	followerClient := new(fakeEventClient)
+	follower := New(store, leader.resolver, followerClient, time.UTC, LeadTimes{})
+
+	leader.Lead(ctx)
+
+	// give the leader time to deliver the initial (empty) outbox
+	time.Sleep(10 * time.Millisecond)
+
+	if err := follower.PublishState(ctx, ""); err != nil {
+		t.Fatalf("failed to publish state: %s", err)
+	}
+
+	if entries, _ := store.PendingEvents(ctx); len(entries) == 0 {
+		t.Fatalf("expected the follower to enqueue events")
+	}
+
+	// notify the leader like the change stream does
+	leader.Dispatch()
+
+	for deadline := time.Now().Add(time.Second); ; {
+		entries, _ := store.PendingEvents(ctx)
+		if len(entries) == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the leader to deliver the events of the follower, got %d pending events", len(entries))
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if len(followerClient.published) != 0 {
+		t.Errorf("expected the follower to leave the delivery to the leader, got %d events", len(followerClient.published))
+	}
+}
+
+func TestPollOutbox(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leader, store, _ := newTestWatcher(t, LeadTimes{})
+	leader.SetPollInterval(10 * time.Millisecond)
+
+	follower := New(store, leader.resolver, new(fakeEventClient), time.UTC, LeadTimes{})
+
+	leader.Lead(ctx)
+
+	if err := follower.PublishState(ctx, ""); err != nil {
+		t.Fatalf("failed to publish state: %s", err)
+	}
+
+	// without a notification the leader finds the events when polling
+	for deadline := time.Now().Add(time.Second); ; {
+		entries, _ := store.PendingEvents(ctx)
+		if len(entries) == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the leader to poll the outbox, got %d pending events", len(entries))
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPublishModeChange(t *testing.T) {
+	ctx := context.Background()
+
+	w, store, _ := newTestWatcher(t, LeadTimes{})
+
+	if _, err := store.UpsertOfficeHours(ctx, &repo.OfficeHourModel{
+		DayOfWeek: time.Tuesday,
+		TimeRanges: []repo.DayTimeRange{
+			{Start: repo.DayTime{Hours: 8}, End: repo.DayTime{Hours: 10}},
+			{Start: repo.DayTime{Hours: 10}, End: repo.DayTime{Hours: 12}, Mode: repo.ModeEmergencyOnly},
+		},
+	}); err != nil {
+		t.Fatalf("failed to upsert office hour: %s", err)
+	}
+
+	published := make(map[string]publishedState)
+
+	for _, now := range []time.Time{
+		time.Date(2024, time.June, 4, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, time.June, 4, 9, 30, 0, 0, time.UTC),
+		time.Date(2024, time.June, 4, 11, 0, 0, 0, time.UTC),
+	} {
+		state, err := w.check(ctx, now, "")
+		if err != nil {
+			t.Fatalf("failed to resolve open state: %s", err)
+		}
+
+		if err := w.publishChange(ctx, now, "", state, published); err != nil {
+			t.Fatalf("failed to publish: %s", err)
+		}
+	}
+
+	entries, err := store.PendingEvents(ctx)
+	if err != nil {
+		t.Fatalf("failed to load pending events: %s", err)
+	}
+
+	// the unchanged state at 09:30 is not published again and the change of
+	// the opening mode only publishes a LocationOpenChangeEvent
+	expected := []struct {
+		kind string
+		mode repo.OpeningMode
+	}{
+		{locationOpenChangeEvent, repo.ModeConsultation},
+		{"tkd.office_hours.v1.OpenChangeEvent", ""},
+		{locationOpenChangeEvent, repo.ModeEmergencyOnly},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d events but got %d", len(expected), len(entries))
+	}
+
+	for idx, entry := range entries {
+		pb, err := entry.Any()
+		if err != nil {
+			t.Fatalf("failed to decode event: %s", err)
+		}
+
+		if kind := eventKind(pb); kind != expected[idx].kind {
+			t.Fatalf("expected event %d to be of kind %q but got %q", idx, expected[idx].kind, kind)
+		}
+
+		if expected[idx].kind != locationOpenChangeEvent {
+			continue
+		}
+
+		event := new(extv1.LocationOpenChangeEvent)
+		if err := pb.UnmarshalTo(event); err != nil {
+			t.Fatalf("failed to decode event: %s", err)
+		}
+
+		if !event.IsOpen || event.Mode != string(expected[idx].mode) {
+			t.Errorf("expected event %d to be open with mode %q but got open=%t mode=%q", idx, expected[idx].mode, event.IsOpen, event.Mode)
+		}
+	}
+}